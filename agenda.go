@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// agendaSection and agendaTask are the machine-readable agenda. Days
+// counts from today to the due date and is negative for late tasks.
+type agendaSection struct {
+	Title string       `json:"title"`
+	Date  string       `json:"date,omitempty"`
+	Tasks []agendaTask `json:"tasks"`
+}
+
+type agendaTask struct {
+	todotxt.Record
+	Days  *int   `json:"days,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+func agendaCommand(args []string) error {
+	fs := flag.NewFlagSet("agenda", flag.ContinueOnError)
+	days := fs.Int("days", 7, "number of days to show, starting today")
+	minPriority := fs.String("priority", "B", "lowest priority of undated tasks to show")
+	format := formatFlag(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if *days < 1 {
+		return fmt.Errorf("invalid --days: %d (must be at least 1)", *days)
+	}
+	p := strings.ToUpper(*minPriority)
+	if len(p) != 1 || p[0] < 'A' || p[0] > 'Z' {
+		return fmt.Errorf("invalid priority: %s (must be A-Z)", *minPriority)
+	}
+
+	todos, err := selectTodos(args, false)
+	if err != nil {
+		return err
+	}
+	// A filter shows deferred tasks everywhere else; the agenda never does.
+	todos = todotxt.FilterTodos(todos, todotxt.Not(todotxt.IsDeferred(todotxt.Now())))
+
+	today := todotxt.Today()
+	var sections []agendaSection
+	for _, s := range todotxt.Agenda(todos, *days, todotxt.Priority(p[0])) {
+		section := agendaSection{Title: s.Title, Tasks: []agendaTask{}}
+		if s.Date != nil {
+			section.Date = s.Date.Format("2006-01-02")
+		}
+		for _, todo := range s.Todos {
+			task := agendaTask{Record: todo.Record()}
+			if due := todo.GetDueDate(); due != nil {
+				n := daysBetween(today, *due)
+				task.Days = &n
+				task.Label = dueLabel(n)
+			}
+			section.Tasks = append(section.Tasks, task)
+		}
+		sections = append(sections, section)
+	}
+
+	if *format != "text" {
+		return writeAgenda(*format, sections)
+	}
+
+	printed := 0
+	for _, section := range sections {
+		if len(section.Tasks) == 0 {
+			continue
+		}
+		if printed > 0 {
+			fmt.Println()
+		}
+		printed++
+
+		title := section.Title
+		if section.Date != "" {
+			date, _ := time.Parse("2006-01-02", section.Date)
+			title = date.Format("Mon 2006-01-02")
+			if section.Title != date.Weekday().String() {
+				title = section.Title + ", " + title
+			}
+		}
+		fmt.Printf("%s (%d)\n", title, len(section.Tasks))
+		for _, task := range section.Tasks {
+			todo := todoFile.GetByID(task.ID)
+			line := fmt.Sprintf("[ ] %3d: %s", task.ID, task.Text)
+			if task.Label != "" {
+				line += " (" + task.Label + ")"
+			}
+			fmt.Println(colorize(todoColor(todo), line))
+		}
+	}
+	if printed == 0 {
+		fmt.Println("Nothing on the agenda.")
+	}
+	return nil
+}
+
+// writeAgenda writes an agenda as a JSON array of sections, one section
+// per NDJSON line, or one CSV or TSV row per task.
+func writeAgenda(format string, sections []agendaSection) error {
+	switch format {
+	case todotxt.FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sections)
+	case todotxt.FormatNDJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		for _, section := range sections {
+			if err := encoder.Encode(section); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	table := [][]string{{"section", "date", "days", "label", "id", "priority", "text"}}
+	for _, section := range sections {
+		for _, task := range section.Tasks {
+			days := ""
+			if task.Days != nil {
+				days = strconv.Itoa(*task.Days)
+			}
+			table = append(table, []string{section.Title, section.Date, days, task.Label, strconv.Itoa(task.ID), task.Priority, task.Text})
+		}
+	}
+	return todotxt.WriteTable(os.Stdout, format, table)
+}
+
+// daysBetween counts calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+func relativeDays(days int) string {
+	switch {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == -1:
+		return "yesterday"
+	case days > 0:
+		return fmt.Sprintf("in %d days", days)
+	}
+	return fmt.Sprintf("%d days ago", -days)
+}
+
+// dueLabel describes a due date days away, such as "in 2 days" or
+// "3 days late".
+func dueLabel(days int) string {
+	switch {
+	case days == -1:
+		return "1 day late"
+	case days < 0:
+		return fmt.Sprintf("%d days late", -days)
+	}
+	return relativeDays(days)
+}
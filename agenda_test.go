@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+func TestAgendaCommandHidesDeferredTasks(t *testing.T) {
+	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "No filter"},
+		{name: "Filter", args: []string{"+Work"}},
+		{name: "All", args: []string{"all", "+Work"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestTodoFile(t,
+				"Send report +Work due:2025-01-11",
+				"Plan offsite +Work due:2025-01-12 t:2025-01-13",
+			)
+			out, err := captureStdout(t, func() error { return agendaCommand(tt.args) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, "Send report") || strings.Contains(out, "Plan offsite") {
+				t.Errorf("agenda %q should hide the deferred task, got:\n%s", tt.args, out)
+			}
+		})
+	}
+}
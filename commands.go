@@ -1,12 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
 )
 
 type Command struct {
@@ -15,21 +21,14 @@ type Command struct {
 	Execute     func([]string) error
 }
 
-var todoFile *TodoFile
+var todoFile *todotxt.TodoFile
 
 func initTodoFile() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+	todoFile = todotxt.NewTodoFile(todoPath())
+	if err := todoFile.Lock(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error locking todo file: %v\n", err)
 		os.Exit(1)
 	}
-
-	todoPath := os.Getenv("TODO_FILE")
-	if todoPath == "" {
-		todoPath = homeDir + "/todo.txt"
-	}
-
-	todoFile = NewTodoFile(todoPath)
 	if err := todoFile.Load(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading todo file: %v\n", err)
 		os.Exit(1)
@@ -37,18 +36,73 @@ func initTodoFile() {
 }
 
 func saveFile() error {
-	return todoFile.Save()
+	err := todoFile.Save()
+	if errors.Is(err, todotxt.ErrConflict) {
+		return fmt.Errorf("%w; nothing was saved, re-run the command", err)
+	}
+	return err
+}
+
+// resolveTodo finds a task by line number or stable id: value.
+func resolveTodo(ref string) (*todotxt.Todo, error) {
+	todo := todoFile.Lookup(ref)
+	if todo == nil {
+		return nil, fmt.Errorf("task %s not found", ref)
+	}
+	return todo, nil
+}
+
+// stableIDsEnabled reports whether add should give new tasks an id: tag.
+func stableIDsEnabled() bool {
+	if value, ok := os.LookupEnv("TODO_STABLE_IDS"); ok {
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	return settings.StableIDs != nil && *settings.StableIDs
+}
+
+// addTask appends a new task, dating it and giving it an id: tag as
+// configured. An id: tag the task brings along, from an import for
+// example, is dropped if a task in todo.txt or done.txt already uses it;
+// a new id is never used by one.
+func addTask(todo *todotxt.Todo) error {
+	var doneFile *todotxt.TodoFile
+	if stableIDsEnabled() || todo.StableID() != "" {
+		doneFile = todotxt.NewTodoFile(donePath())
+		if err := doneFile.Load(); err != nil {
+			return fmt.Errorf("failed to load archive file: %w", err)
+		}
+	}
+
+	if id := todo.StableID(); id != "" && (todoFile.GetByStableID(id) != nil || doneFile.GetByStableID(id) != nil) {
+		delete(todo.Tags, todotxt.IDTag)
+	}
+	if todo.CreationDate == nil && !todo.Complete &&
+		settings.AutoCreationDate != nil && *settings.AutoCreationDate {
+		today := todotxt.Today()
+		todo.CreationDate = &today
+	}
+	todoFile.Add(todo)
+	if stableIDsEnabled() {
+		todoFile.AssignStableID(todo, doneFile)
+	}
+	return nil
 }
 
 func addCommand(args []string) error {
-	if len(args) == 0 {
+	description := strings.Join(args, " ")
+	if strings.TrimSpace(description) == "" {
 		return fmt.Errorf("no task description provided")
 	}
 
-	description := strings.Join(args, " ")
-	todo, _ := ParseTodo(description)
+	todo, _ := todotxt.ParseTodo(description)
+	if err := todo.ResolveDates(todotxt.Now()); err != nil {
+		return err
+	}
 
-	todoFile.Add(todo)
+	if err := addTask(todo); err != nil {
+		return err
+	}
 
 	if err := saveFile(); err != nil {
 		return fmt.Errorf("failed to save: %w", err)
@@ -59,26 +113,37 @@ func addCommand(args []string) error {
 }
 
 func listCommand(args []string) error {
-	todos := todoFile.GetIncomplete()
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	sortSpec := fs.String("sort", settings.Sort, "sort keys, e.g. priority,due,-created")
+	groupBy := fs.String("group", "", "group by project, context, priority or tag:<key>")
+	allThresholds := fs.Bool("all-thresholds", false, "include tasks deferred by a future t: date")
+	format := formatFlag(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
 
-	if len(args) > 0 {
-		switch args[0] {
-		case "all":
-			todos = todoFile.Todos
-		case "done":
-			todos = todoFile.GetCompleted()
-		default:
-			if strings.HasPrefix(args[0], "+") {
-				project := strings.TrimPrefix(args[0], "+")
-				todos = todoFile.FilterByProject(project)
-			} else if strings.HasPrefix(args[0], "@") {
-				context := strings.TrimPrefix(args[0], "@")
-				todos = todoFile.FilterByContext(context)
-			} else {
-				query := strings.Join(args, " ")
-				todos = todoFile.Search(query)
-			}
+	todos, err := selectTodos(args, *allThresholds)
+	if err != nil {
+		return err
+	}
+
+	if *sortSpec != "" {
+		keys, err := todotxt.ParseSortKeys(*sortSpec)
+		if err != nil {
+			return err
 		}
+		todotxt.SortTodosBy(todos, keys)
+	}
+
+	if *format != "text" {
+		if *groupBy != "" {
+			return fmt.Errorf("--group is only supported with text output")
+		}
+		return todotxt.WriteRecords(os.Stdout, *format, todos)
 	}
 
 	if len(todos) == 0 {
@@ -86,15 +151,113 @@ func listCommand(args []string) error {
 		return nil
 	}
 
+	if *groupBy == "" {
+		printTodos(todos)
+		return nil
+	}
+
+	groups, err := todotxt.GroupTodos(todos, *groupBy)
+	if err != nil {
+		return err
+	}
+
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s (%d)\n", group.Label, len(group.Todos))
+		printTodos(group.Todos)
+	}
+
+	return nil
+}
+
+// selectTodos returns the tasks a list filter selects: incomplete tasks by
+// default, or all, done, or the tasks matching a query, which may follow
+// all or done. The default view hides tasks deferred by a future t: date
+// unless showDeferred is set.
+func selectTodos(args []string, showDeferred bool) ([]*todotxt.Todo, error) {
+	todos, err := filterTodos(args, showDeferred)
+	if err != nil {
+		return nil, queryError(strings.Join(args, " "), err)
+	}
+	return todos, nil
+}
+
+// filterTodos is selectTodos without the caret display of query errors.
+// Without arguments it applies the configured default filter. The filter
+// is the arguments joined by spaces, so the API and the tui, which pass it
+// as one argument, select the same tasks as list.
+func filterTodos(args []string, showDeferred bool) ([]*todotxt.Todo, error) {
+	if len(args) == 0 {
+		var todos []*todotxt.Todo
+		var err error
+		if settings.Filter != "" {
+			todos, err = filterTodos([]string{settings.Filter}, true)
+		} else {
+			todos = todoFile.GetIncomplete()
+		}
+		if err != nil || showDeferred {
+			return todos, err
+		}
+		return todotxt.FilterTodos(todos, todotxt.Not(todotxt.IsDeferred(todotxt.Now()))), nil
+	}
+
+	query := strings.Join(args, " ")
+	word := ""
+	if fields := strings.Fields(query); len(fields) > 0 {
+		word = fields[0]
+	}
+
+	var todos []*todotxt.Todo
+	switch word {
+	case "all":
+		todos = append([]*todotxt.Todo(nil), todoFile.Todos...)
+	case "done":
+		todos = todoFile.GetCompleted()
+	default:
+		pred, err := todotxt.CompileQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		return todoFile.Filter(pred), nil
+	}
+	rest := strings.TrimLeftFunc(query, unicode.IsSpace)[len(word):]
+	if strings.TrimSpace(rest) == "" {
+		return todos, nil
+	}
+
+	// A query after all or done narrows those tasks down. Errors point
+	// into the whole filter, as the caller shows it.
+	pred, err := todotxt.CompileQuery(rest)
+	var queryErr *todotxt.QueryError
+	if errors.As(err, &queryErr) {
+		return nil, &todotxt.QueryError{Column: queryErr.Column + len(query) - len(rest), Msg: queryErr.Msg}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return todotxt.FilterTodos(todos, pred), nil
+}
+
+func printTodos(todos []*todotxt.Todo) {
 	for _, todo := range todos {
 		status := " "
 		if todo.Complete {
 			status = "x"
 		}
-		fmt.Printf("[%s] %3d: %s\n", status, todo.ID, todo.String())
+		line := fmt.Sprintf("[%s] %3d: %s", status, todo.ID, todo.String())
+		fmt.Println(colorize(todoColor(todo), line))
 	}
+}
 
-	return nil
+// queryError shows where a query failed to parse.
+func queryError(query string, err error) error {
+	var queryErr *todotxt.QueryError
+	if !errors.As(err, &queryErr) {
+		return err
+	}
+	return fmt.Errorf("invalid query: %w\n  %s\n  %s^", err, query, strings.Repeat(" ", queryErr.Column-1))
 }
 
 func completeCommand(args []string) error {
@@ -102,39 +265,55 @@ func completeCommand(args []string) error {
 		return fmt.Errorf("no task ID provided")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	todo, err := resolveTodo(args[0])
 	if err != nil {
-		return fmt.Errorf("invalid task ID: %s", args[0])
+		return err
 	}
 
-	todo := todoFile.GetByID(id)
-	if todo == nil {
-		return fmt.Errorf("task with ID %d not found", id)
+	next, err := completeTodo(todo)
+	if err != nil {
+		return err
 	}
 
-	todo.MarkComplete()
-
 	if err := saveFile(); err != nil {
 		return fmt.Errorf("failed to save: %w", err)
 	}
 
 	fmt.Printf("Completed: %s\n", todo.String())
+	if next != nil {
+		fmt.Printf("Next: %d: %s\n", next.ID, next.String())
+	}
 	return nil
 }
 
+// completeTodo marks todo complete and, if it is an open recurring task,
+// adds its next occurrence, which it returns.
+func completeTodo(todo *todotxt.Todo) (*todotxt.Todo, error) {
+	if todo.Complete {
+		todo.MarkComplete()
+		return nil, nil
+	}
+	next, err := todo.Recur(todotxt.Now())
+	if err != nil {
+		return nil, fmt.Errorf("task %d: %w", todo.ID, err)
+	}
+	todo.MarkComplete()
+	if next != nil {
+		if err := addTask(next); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
 func uncompleteCommand(args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("no task ID provided")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	todo, err := resolveTodo(args[0])
 	if err != nil {
-		return fmt.Errorf("invalid task ID: %s", args[0])
-	}
-
-	todo := todoFile.GetByID(id)
-	if todo == nil {
-		return fmt.Errorf("task with ID %d not found", id)
+		return err
 	}
 
 	todo.MarkUncomplete()
@@ -152,20 +331,15 @@ func deleteCommand(args []string) error {
 		return fmt.Errorf("no task ID provided")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	todo, err := resolveTodo(args[0])
 	if err != nil {
-		return fmt.Errorf("invalid task ID: %s", args[0])
-	}
-
-	todo := todoFile.GetByID(id)
-	if todo == nil {
-		return fmt.Errorf("task with ID %d not found", id)
+		return err
 	}
 
 	description := todo.String()
 
-	if !todoFile.Delete(id) {
-		return fmt.Errorf("failed to delete task with ID %d", id)
+	if !todoFile.Delete(todo.ID) {
+		return fmt.Errorf("failed to delete task %s", args[0])
 	}
 
 	if err := saveFile(); err != nil {
@@ -181,14 +355,9 @@ func priorityCommand(args []string) error {
 		return fmt.Errorf("usage: priority <ID> <A-Z>")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	todo, err := resolveTodo(args[0])
 	if err != nil {
-		return fmt.Errorf("invalid task ID: %s", args[0])
-	}
-
-	todo := todoFile.GetByID(id)
-	if todo == nil {
-		return fmt.Errorf("task with ID %d not found", id)
+		return err
 	}
 
 	priorityStr := strings.ToUpper(args[1])
@@ -196,7 +365,7 @@ func priorityCommand(args []string) error {
 		return fmt.Errorf("invalid priority: %s (must be A-Z)", args[1])
 	}
 
-	todo.SetPriority(Priority(priorityStr[0]))
+	todo.SetPriority(todotxt.Priority(priorityStr[0]))
 
 	if err := saveFile(); err != nil {
 		return fmt.Errorf("failed to save: %w", err)
@@ -206,22 +375,44 @@ func priorityCommand(args []string) error {
 	return nil
 }
 
+func deferCommand(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: defer <ID> <date|+3d>")
+	}
+
+	todo, err := resolveTodo(args[0])
+	if err != nil {
+		return err
+	}
+	if todo.Complete {
+		return fmt.Errorf("task %s is already complete", args[0])
+	}
+
+	date, err := todotxt.ParseDate(args[1], todotxt.Now())
+	if err != nil {
+		return err
+	}
+	todo.AddTag(todotxt.ThresholdTag, date.Format("2006-01-02"))
+
+	if err := saveFile(); err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+
+	fmt.Printf("Deferred until %s: %s\n", date.Format("2006-01-02"), todo.String())
+	return nil
+}
+
 func depriCommand(args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("no task ID provided")
 	}
 
-	id, err := strconv.Atoi(args[0])
+	todo, err := resolveTodo(args[0])
 	if err != nil {
-		return fmt.Errorf("invalid task ID: %s", args[0])
-	}
-
-	todo := todoFile.GetByID(id)
-	if todo == nil {
-		return fmt.Errorf("task with ID %d not found", id)
+		return err
 	}
 
-	todo.SetPriority(PriorityNone)
+	todo.SetPriority(todotxt.PriorityNone)
 
 	if err := saveFile(); err != nil {
 		return fmt.Errorf("failed to save: %w", err)
@@ -231,16 +422,42 @@ func depriCommand(args []string) error {
 	return nil
 }
 
+func todoPath() string {
+	return configuredPath("TODO_FILE", profilePaths.TodoFile, settings.TodoFile, "todo.txt")
+}
+
+func donePath() string {
+	return configuredPath("DONE_FILE", profilePaths.DoneFile, settings.DoneFile, "done.txt")
+}
+
 func archiveCommand(args []string) error {
-	homeDir, _ := os.UserHomeDir()
-	archivePath := os.Getenv("DONE_FILE")
-	if archivePath == "" {
-		archivePath = homeDir + "/done.txt"
+	archived, err := archiveCompleted()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Archived %d completed tasks to %s\n", archived, donePath())
+	return nil
+}
+
+// archiveCompleted moves completed tasks to the done file and saves both
+// files, returning how many tasks were moved.
+func archiveCompleted() (int, error) {
+	archivePath := donePath()
+
+	archiveFile := todotxt.NewTodoFile(archivePath)
+	if err := archiveFile.Lock(); err != nil {
+		return 0, fmt.Errorf("failed to lock archive file: %w", err)
 	}
+	defer archiveFile.Unlock()
 
-	archiveFile := NewTodoFile(archivePath)
 	if err := archiveFile.Load(); err != nil {
-		return fmt.Errorf("failed to load archive file: %w", err)
+		return 0, fmt.Errorf("failed to load archive file: %w", err)
+	}
+
+	// Refuse before touching done.txt so a conflict cannot archive twice.
+	if err := todoFile.CheckConflict(); err != nil {
+		return 0, fmt.Errorf("%w; nothing was archived, re-run the command", err)
 	}
 
 	completed := todoFile.GetCompleted()
@@ -249,45 +466,202 @@ func archiveCommand(args []string) error {
 	}
 
 	if err := archiveFile.Save(); err != nil {
-		return fmt.Errorf("failed to save archive file: %w", err)
+		return 0, fmt.Errorf("failed to save archive file: %w", err)
+	}
+
+	todoFile.RemoveCompleted()
+
+	if err := saveFile(); err != nil {
+		return 0, fmt.Errorf("failed to save todo file: %w", err)
 	}
 
-	var remaining []*Todo
-	for _, todo := range todoFile.Todos {
-		if !todo.Complete {
-			remaining = append(remaining, todo)
+	return len(completed), nil
+}
+
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "input format: json, ndjson, csv or tsv (default from file extension)")
+	dryRun := fs.Bool("dry-run", false, "show what would be added without saving")
+	events := fs.Bool("events", false, "with ical, also import events as \"Prepare for\" tasks")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 && args[0] == "ical" {
+		return importICal(args[1], *dryRun, *events)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: import [--format F] [--dry-run] <file|->\n       import ical [--events] [--dry-run] <file.ics|->")
+	}
+	path := args[0]
+
+	if *format == "" && *outputFormat != "text" {
+		*format = *outputFormat
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if path == "-" || *format == "" {
+			return fmt.Errorf("cannot tell the input format; use --format json, ndjson, csv or tsv")
+		}
+	}
+
+	input := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	todos, rowErrors, err := todotxt.ReadRecords(input, *format)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range rowErrors {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, rowErr)
+	}
+
+	if *dryRun {
+		for i, todo := range todos {
+			fmt.Printf("Would add %d: %s\n", todoFile.NextID()+i, todo.String())
+		}
+		fmt.Printf("%d task(s) valid, %d row(s) invalid; nothing was saved (dry run)\n", len(todos), len(rowErrors))
+		return nil
+	}
+
+	if len(rowErrors) > 0 {
+		return fmt.Errorf("%d invalid row(s); nothing was imported", len(rowErrors))
+	}
+
+	for _, todo := range todos {
+		if err := addTask(todo); err != nil {
+			return err
+		}
+	}
+
+	if err := saveFile(); err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+
+	fmt.Printf("Imported %d task(s)\n", len(todos))
+	return nil
+}
+
+func importICal(path string, dryRun, events bool) error {
+	input := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open calendar: %w", err)
 		}
+		defer f.Close()
+		input = f
+	}
+
+	tasks, rowErrors, err := todotxt.ReadICal(input, todotxt.ICalImportOptions{Events: events})
+	if err != nil {
+		return err
+	}
+	for _, rowErr := range rowErrors {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, rowErr)
+	}
+
+	added, updated, err := todoFile.ImportICal(tasks, addTask)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, todo := range added {
+			fmt.Printf("Would add %d: %s\n", todo.ID, todo.String())
+		}
+		for _, todo := range updated {
+			fmt.Printf("Would update %d: %s\n", todo.ID, todo.String())
+		}
+		fmt.Printf("%d to add, %d to update, %d invalid; nothing was saved (dry run)\n", len(added), len(updated), len(rowErrors))
+		return nil
+	}
+
+	if len(rowErrors) > 0 {
+		return fmt.Errorf("%d invalid component(s); nothing was imported", len(rowErrors))
 	}
-	todoFile.Todos = remaining
-	todoFile.reindexTodos()
 
 	if err := saveFile(); err != nil {
-		return fmt.Errorf("failed to save todo file: %w", err)
+		return fmt.Errorf("failed to save: %w", err)
+	}
+
+	for _, todo := range updated {
+		fmt.Printf("Updated %d: %s\n", todo.ID, todo.String())
+	}
+	fmt.Printf("Imported %d new task(s), updated %d\n", len(added), len(updated))
+	return nil
+}
+
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("output", "", "write to a file instead of standard output")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: export ical [--output FILE] [filter]")
+	}
+	if args[0] != "ical" {
+		return fmt.Errorf("unsupported export format: %s (must be ical)", args[0])
+	}
+
+	todos, err := selectTodos(args[1:], true)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Archived %d completed tasks to %s\n", len(completed), archivePath)
+	if *output == "" {
+		return todotxt.WriteICal(os.Stdout, todos, todotxt.Now())
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := todotxt.WriteICal(f, todos, todotxt.Now()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d task(s) to %s\n", len(todos), *output)
 	return nil
 }
 
 func projectsCommand(args []string) error {
-	projectMap := make(map[string]int)
+	fs := flag.NewFlagSet("projects", flag.ContinueOnError)
+	format := formatFlag(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
 
 	todos := todoFile.Todos
 	if len(args) > 0 && args[0] == "all" {
 		// Include completed tasks
 	} else {
-		// Only count incomplete tasks by default
-		todos = todoFile.GetIncomplete()
+		// Only count active tasks by default
+		todos = todoFile.GetActive(todotxt.Now())
 	}
 
-	for _, todo := range todos {
-		if len(todo.Projects) == 0 {
-			projectMap["(none)"]++
-		} else {
-			for _, project := range todo.Projects {
-				projectMap[project]++
-			}
-		}
+	projectMap := countNames(todos, func(todo *todotxt.Todo) []string { return todo.Projects })
+
+	if *format != "text" {
+		return writeTally(*format, projectMap)
 	}
 
 	if len(projectMap) == 0 {
@@ -305,8 +679,8 @@ func projectsCommand(args []string) error {
 	fmt.Println("Projects:")
 	for _, project := range projects {
 		count := projectMap[project]
-		if project == "(none)" {
-			fmt.Printf("  %s: %d task(s)\n", project, count)
+		if project == "" {
+			fmt.Printf("  (none): %d task(s)\n", count)
 		} else {
 			fmt.Printf("  +%s: %d task(s)\n", project, count)
 		}
@@ -316,24 +690,28 @@ func projectsCommand(args []string) error {
 }
 
 func contextsCommand(args []string) error {
-	contextMap := make(map[string]int)
+	fs := flag.NewFlagSet("contexts", flag.ContinueOnError)
+	format := formatFlag(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
 
 	todos := todoFile.Todos
 	if len(args) > 0 && args[0] == "all" {
 		// Include completed tasks
 	} else {
-		// Only count incomplete tasks by default
-		todos = todoFile.GetIncomplete()
+		// Only count active tasks by default
+		todos = todoFile.GetActive(todotxt.Now())
 	}
 
-	for _, todo := range todos {
-		if len(todo.Contexts) == 0 {
-			contextMap["(none)"]++
-		} else {
-			for _, context := range todo.Contexts {
-				contextMap[context]++
-			}
-		}
+	contextMap := countNames(todos, func(todo *todotxt.Todo) []string { return todo.Contexts })
+
+	if *format != "text" {
+		return writeTally(*format, contextMap)
 	}
 
 	if len(contextMap) == 0 {
@@ -351,8 +729,8 @@ func contextsCommand(args []string) error {
 	fmt.Println("Contexts:")
 	for _, context := range contexts {
 		count := contextMap[context]
-		if context == "(none)" {
-			fmt.Printf("  %s: %d task(s)\n", context, count)
+		if context == "" {
+			fmt.Printf("  (none): %d task(s)\n", count)
 		} else {
 			fmt.Printf("  @%s: %d task(s)\n", context, count)
 		}
@@ -361,6 +739,64 @@ func contextsCommand(args []string) error {
 	return nil
 }
 
+func lintCommand(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := formatFlag(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	path := todoFile.Path
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	diagnostics, err := todotxt.Lint(file)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "text":
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s\n", path, d)
+		}
+	case "json":
+		if diagnostics == nil {
+			diagnostics = []todotxt.Diagnostic{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			File        string               `json:"file"`
+			Diagnostics []todotxt.Diagnostic `json:"diagnostics"`
+		}{path, diagnostics}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid format for lint: %s (must be text or json)", *format)
+	}
+
+	errorCount := 0
+	for _, d := range diagnostics {
+		if d.Severity == todotxt.SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%s: %d error(s) found", path, errorCount)
+	}
+
+	return nil
+}
+
 func helpCommand(args []string) error {
 	if len(args) > 0 {
 		return helpForCommand(args[0])
@@ -379,6 +815,7 @@ func helpCommand(args []string) error {
 	fmt.Println("TASK MANAGEMENT:")
 	fmt.Println("  add <task>               Add a new task")
 	fmt.Println("  list, ls [filter]        List tasks (default: incomplete)")
+	fmt.Println("  agenda [--days N]        Overdue, today's and upcoming tasks by day")
 	fmt.Println("  do, done <ID>            Mark task as complete")
 	fmt.Println("  undo <ID>                Mark task as incomplete")
 	fmt.Println("  delete, rm <ID>          Delete a task")
@@ -386,11 +823,29 @@ func helpCommand(args []string) error {
 	fmt.Println("PRIORITY MANAGEMENT:")
 	fmt.Println("  priority, pri <ID> <A-Z> Set task priority (A=highest)")
 	fmt.Println("  depri <ID>               Remove task priority")
+	fmt.Println("  defer <ID> <date|+3d>    Hide a task until a date (t: tag)")
 	fmt.Println()
 	fmt.Println("ORGANIZATION:")
 	fmt.Println("  projects, proj [all]     List all projects with task counts")
 	fmt.Println("  contexts, ctx [all]      List all contexts with task counts")
 	fmt.Println("  archive                  Move completed tasks to done.txt")
+	fmt.Println("  import [--dry-run] FILE  Append tasks from JSON, NDJSON, CSV or TSV")
+	fmt.Println("  import ical FILE.ics     Add or update tasks from calendar VTODOs")
+	fmt.Println("  export ical [filter]     Write tasks as an iCalendar file of VTODOs")
+	fmt.Println()
+	fmt.Println("INTERACTIVE:")
+	fmt.Println("  tui [filter]             Browse and edit tasks in the terminal")
+	fmt.Println("  serve [--addr ADDR]      Serve a web UI and HTTP/JSON API")
+	fmt.Println()
+	fmt.Println("HISTORY:")
+	fmt.Println("  undo-last                Revert the last change to todo.txt/done.txt")
+	fmt.Println("  redo                     Re-apply the last reverted change")
+	fmt.Println("  history [N]              Show the last N recorded changes")
+	fmt.Println()
+	fmt.Println("MAINTENANCE:")
+	fmt.Println("  lint [--format F] [file] Check a todo.txt file for problems")
+	fmt.Println("  lsp                      Run a language server for editors (stdio)")
+	fmt.Println("  completion SHELL         Print a bash, zsh or fish completion script")
 	fmt.Println()
 	fmt.Println("LIST FILTERS:")
 	fmt.Println("  list                     Show incomplete tasks")
@@ -399,6 +854,26 @@ func helpCommand(args []string) error {
 	fmt.Println("  list +Project            Filter by project")
 	fmt.Println("  list @Context            Filter by context")
 	fmt.Println("  list <search>            Search in task descriptions")
+	fmt.Println("  list <query>             Filter with a query, e.g.")
+	fmt.Println("                           '+Work and not pri:C and due<=2025-02-01'")
+	fmt.Println("  list --sort KEYS         Sort, e.g. --sort priority,due,-created")
+	fmt.Println("  list --group BY          Group by project, context, priority or tag:<key>")
+	fmt.Println("  list --all-thresholds    Include tasks deferred by a future t: date")
+	fmt.Println()
+	fmt.Println("OUTPUT FORMATS:")
+	fmt.Println("  --format F               text (default), json, ndjson, csv or tsv;")
+	fmt.Println("                           accepted by list, agenda, projects and")
+	fmt.Println("                           contexts, before or after the command name")
+	fmt.Println()
+	fmt.Println("CONFIGURATION:")
+	fmt.Println("  --profile NAME           Use a profile from the config file")
+	fmt.Println("  --color WHEN             Colorize list output: auto, always or never")
+	fmt.Println("  help config              Config file location and settings")
+	fmt.Println()
+	fmt.Println("DATES:")
+	fmt.Println("  Dates in due: and t: tags and in date arguments may be relative,")
+	fmt.Println("  like today, fri, next-mon, eom or +2w; added tasks store YYYY-MM-DD.")
+	fmt.Println("  help dates               The full date syntax")
 	fmt.Println()
 	fmt.Println("TASK FORMAT:")
 	fmt.Println("  (A) Task description +project @context key:value")
@@ -408,12 +883,20 @@ func helpCommand(args []string) error {
 	fmt.Println("    x            Completed task")
 	fmt.Println("    +project     Project tag")
 	fmt.Println("    @context     Context tag")
-	fmt.Println("    due:date     Due date (format: YYYY-MM-DD)")
+	fmt.Println("    due:date     Due date (YYYY-MM-DD or a relative date)")
+	fmt.Println("    t:date       Threshold date; hidden until then")
+	fmt.Println("    id:value     Stable task ID")
+	fmt.Println("    rec:1w       Recur on completion (d, w, m, y, b; +1m is strict)")
 	fmt.Println("    key:value    Custom metadata")
 	fmt.Println()
+	fmt.Println("TASK IDS:")
+	fmt.Println("  <ID> is either a line number or the value of a task's id: tag.")
+	fmt.Println("  Line numbers shift after delete and archive; id: values do not.")
+	fmt.Println()
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  todotxt add \"(A) Call Mom +Family @phone\"")
 	fmt.Println("  todotxt add \"Submit report +Work @office due:2025-01-15\"")
+	fmt.Println("  todotxt add \"Pay rent +Home due:fri\"")
 	fmt.Println("  todotxt list +Work")
 	fmt.Println("  todotxt do 3")
 	fmt.Println("  todotxt priority 5 B")
@@ -421,6 +904,7 @@ func helpCommand(args []string) error {
 	fmt.Println("ENVIRONMENT:")
 	fmt.Println("  TODO_FILE     Path to todo.txt (default: ~/todo.txt)")
 	fmt.Println("  DONE_FILE     Path to done.txt (default: ~/done.txt)")
+	fmt.Println("  TODO_STABLE_IDS  Give tasks an id: tag on add (true/false)")
 	fmt.Println()
 	fmt.Println("For more information on a specific command, run:")
 	fmt.Println("  todotxt help <command>")
@@ -437,12 +921,19 @@ USAGE:
 
 DESCRIPTION:
   Adds a new task to your todo.txt file. The task can include priority,
-  projects, contexts, and custom tags.
+  projects, contexts, and custom tags. When TODO_STABLE_IDS is set, the
+  task also gets a unique id: tag that other commands accept as its ID.
+  With auto_creation_date in the config file, tasks without a creation
+  date get today's date.
+
+  Relative due: and t: dates such as fri, tomorrow or +2w are stored as
+  YYYY-MM-DD dates (see help dates); other values are rejected.
 
 EXAMPLES:
   todotxt add "Buy milk"
   todotxt add "(A) Important meeting +Work @office"
   todotxt add "Submit report +Work due:2025-01-15"
+  todotxt add "Pay rent +Home due:fri t:-3d"
   todotxt add "(B) Call dentist @phone +Health"
 
 TASK FORMAT:
@@ -451,30 +942,106 @@ TASK FORMAT:
   - @context   Context tag (can have multiple)
   - key:value  Custom tags (e.g., due:2025-01-15)`,
 
+		"agenda": `AGENDA COMMAND - Show what is due
+
+USAGE:
+  todotxt agenda [--days N] [--priority P] [--format F] [filter]
+
+DESCRIPTION:
+  Shows open tasks in sections: Overdue, Today, Tomorrow and each
+  following day up to N days from today (default 7), then tasks without
+  a due date at priority P or higher (default B). Each dated task is
+  labeled with how far away it is, like "3 days late" or "in 2 days".
+  Empty sections are left out of text output.
+
+  Deferred tasks are hidden. A filter selects tasks as list does.
+
+OPTIONS:
+  --days N       Days to show, starting today
+  --priority P   Lowest priority of undated tasks to show
+  --format F     text, json, ndjson, csv or tsv. JSON is an array of
+                 sections with title, date and tasks; each task is a
+                 list --format json record plus days (negative when
+                 late) and label.
+
+EXAMPLES:
+  todotxt agenda
+  todotxt agenda --days 3 +Work
+  todotxt agenda --format json`,
+
 		"list": `LIST COMMAND - Display tasks
 
 USAGE:
-  todotxt list [filter]
-  todotxt ls [filter]
+  todotxt list [--sort KEYS] [--group BY] [--all-thresholds] [--format F] [filter]
+  todotxt ls [--sort KEYS] [--group BY] [--all-thresholds] [--format F] [filter]
 
 DESCRIPTION:
-  Lists tasks from your todo.txt file. By default shows incomplete tasks.
+  Lists tasks from your todo.txt file. By default shows incomplete tasks,
+  or the tasks matching the config file's filter setting. The config
+  file's sort setting is the default for --sort.
+
+  Tasks with a t: (threshold) date after today are hidden from the
+  default view until that date. Filters always include them.
 
 FILTERS:
-  (none)       Show incomplete tasks
+  (none)       Show incomplete tasks that are not deferred
   all          Show all tasks
   done         Show completed tasks only
-  +Project     Filter by project
-  @Context     Filter by context
-  <search>     Search in descriptions
+  <query>      Show tasks matching a query (see below)
+  all <query>, done <query>
+               Show the tasks of all or done that match the query
+
+OPTIONS:
+  --sort KEYS  Comma-separated sort keys: id, priority, created,
+               completed, due, description, complete, project,
+               context, or any tag key (tag:<key> if it clashes with
+               a field). Prefix a key with - to sort descending.
+               Tasks without a value sort last.
+  --group BY   Print sections with counts, grouped by project,
+               context, priority or tag:<key>.
+  --all-thresholds
+               Include deferred tasks in the default view.
+  --format F   text (default), json, ndjson, csv or tsv. Machine
+               formats write one record per task with the fields
+               id, complete, priority, creation_date, completion_date,
+               description, projects, contexts, tags and text.
+
+QUERIES:
+  Terms can be combined with and, or, not and parentheses. Terms next
+  to each other without an operator must all match.
+
+  +Project            Task has the project
+  @Context            Task has the context
+  word, "a phrase"    Text in the description, projects or contexts
+  key:value           Field or tag equals value
+  key<op>value        Compare with =, !=, <, <=, >, >=
+  is:done, is:open    Task is (not) complete
+  is:overdue          Open task past its due date
+  is:deferred         Open task with a t: date after today
+  has:key             Task has the tag
+
+  Fields: pri (priority), created and completed (dates); any other key
+  is a tag. Field names are case-insensitive, tag keys are not, and a
+  word that is not a tag in a task, like a URL, is searched for. Dates are YYYY-MM-DD or relative (today, fri, eom, -1w;
+  see help dates), and durations like 90m, 2h, 1d or 1w compare by length.
 
 EXAMPLES:
   todotxt list                 # Show incomplete tasks
   todotxt list all            # Show all tasks
   todotxt list done           # Show completed tasks
+  todotxt list done +Work     # Show completed tasks in Work project
   todotxt list +Work          # Show tasks in Work project
   todotxt list @home          # Show tasks in home context
-  todotxt list "report"       # Search for "report"`,
+  todotxt list "report"       # Search for "report"
+  todotxt list '+Work and @office and not pri:C'
+  todotxt list 'due<=2025-02-01 or est>2h'
+  todotxt list 'is:open and (@phone or @errands)'
+  todotxt list --sort priority,due,-created
+  todotxt list --sort -est +Work
+  todotxt list --group project
+  todotxt list all --group tag:sprint --sort due
+  todotxt list --format json +Work
+  todotxt --format csv list all`,
 
 		"do": `DO/DONE COMMAND - Mark task as complete
 
@@ -487,10 +1054,20 @@ DESCRIPTION:
   Marks a task as complete. This adds an 'x' marker and completion date
   to the task, and removes any priority.
 
+  A task with a rec: tag is followed by a new task for its next
+  occurrence, keeping its priority, projects, contexts and tags:
+
+  rec:1w       Due 1 week after today
+  rec:+1m      Due 1 month after the previous due date (strict)
+  rec:2b       Due 2 business days after today
+
+  Units are d (days), w (weeks), m (months), y (years) and b (business
+  days). A t: date moves by the same number of days as due:.
+
 EXAMPLES:
   todotxt do 3
   todotxt done 1
-  todotxt complete 5`,
+  todotxt complete k3m9qa     # Task with id:k3m9qa`,
 
 		"priority": `PRIORITY COMMAND - Set task priority
 
@@ -510,8 +1087,8 @@ EXAMPLES:
 		"projects": `PROJECTS COMMAND - List all projects
 
 USAGE:
-  todotxt projects [all]
-  todotxt proj [all]
+  todotxt projects [--format F] [all]
+  todotxt proj [--format F] [all]
 
 DESCRIPTION:
   Lists all unique projects found in tasks, along with the count of
@@ -520,6 +1097,9 @@ DESCRIPTION:
 
 OPTIONS:
   all          Include completed tasks in counts
+  --format F   text (default), json, ndjson, csv or tsv. Machine
+               formats write name and count fields; tasks without
+               a project are counted under an empty name.
 
 EXAMPLES:
   todotxt projects            # Projects from incomplete tasks
@@ -528,8 +1108,8 @@ EXAMPLES:
 		"contexts": `CONTEXTS COMMAND - List all contexts
 
 USAGE:
-  todotxt contexts [all]
-  todotxt ctx [all]
+  todotxt contexts [--format F] [all]
+  todotxt ctx [--format F] [all]
 
 DESCRIPTION:
   Lists all unique contexts found in tasks, along with the count of
@@ -538,6 +1118,9 @@ DESCRIPTION:
 
 OPTIONS:
   all          Include completed tasks in counts
+  --format F   text (default), json, ndjson, csv or tsv. Machine
+               formats write name and count fields; tasks without
+               a context are counted under an empty name.
 
 EXAMPLES:
   todotxt contexts            # Contexts from incomplete tasks
@@ -560,6 +1143,278 @@ NOTES:
 EXAMPLE:
   todotxt archive`,
 
+		"import": `IMPORT COMMAND - Append tasks from JSON or CSV
+
+USAGE:
+  todotxt import [--format F] [--dry-run] <file|->
+  todotxt import ical [--events] [--dry-run] <file.ics|->
+
+DESCRIPTION:
+  Reads tasks in the schema written by list --format (see README) and
+  appends them to todo.txt. Every row is validated first, including the
+  checks of lint; invalid rows are reported with their row number and
+  nothing is imported until all rows are valid. Use - to read from
+  standard input.
+
+  A text field, when present, is used as the task's todo.txt line. Other
+  rows are built from priority, creation_date, completion_date, complete,
+  description, projects, contexts and tags. Any other field or column
+  becomes a tag, except id, the line number, which is ignored. Tasks are
+  added like add adds them: relative due: and t: dates are resolved,
+  auto_creation_date and stable IDs apply, and an id: tag already used by
+  a task in todo.txt or done.txt is dropped, or replaced with a new one
+  when stable IDs are on.
+
+  import ical reads VTODOs from an iCalendar file. SUMMARY becomes the
+  description, PRIORITY 1-9 becomes A-I, DUE becomes due:, DTSTART t:,
+  CATEGORIES +projects and @contexts (bare names become projects), and
+  RRULE a strict rec: tag. Date-times with a TZID or in UTC are converted
+  to the configured timezone, or the local one. Each task keeps its UID
+  in a uid: tag, so importing the same file again updates those tasks
+  instead of adding them twice. Tasks exported with export ical are
+  matched as well, and get a uid: tag when an import changes them. Imported
+  into another file, they get back their id: tag, or a uid: tag with their
+  UID, and are added like add would, with the same id: checks.
+  Cancelled tasks are skipped.
+
+OPTIONS:
+  --format F   json, ndjson, csv or tsv (default: the file extension)
+  --dry-run    Show the tasks that would be added and any row errors
+               without changing todo.txt
+  --events     With ical, also add a "Prepare for <summary>" task due on
+               the day each VEVENT starts
+
+EXAMPLES:
+  todotxt import tasks.csv
+  todotxt import --dry-run export.json
+  other-tool dump | todotxt import --format ndjson -
+  todotxt import ical --events calendar.ics`,
+
+		"export": `EXPORT COMMAND - Export tasks to a calendar
+
+USAGE:
+  todotxt export ical [--output FILE] [filter]
+
+DESCRIPTION:
+  Writes an RFC 5545 iCalendar file with one VTODO per task. The filter
+  works like list: open tasks by default, or all, done, or a query.
+
+  SUMMARY       Description
+  PRIORITY      A-H become 1-8, I-Z become 9
+  DUE           due: date
+  DTSTART       t: threshold date, if before due:
+  CREATED       Creation date
+  COMPLETED     Completion date, with STATUS:COMPLETED
+  CATEGORIES    +projects and @contexts
+  DESCRIPTION   The full todo.txt line
+
+  The UID comes from the task's id: tag when it has one, otherwise from
+  its creation date and description, so it stays the same across
+  exports. Identical tasks get -2, -3 and so on appended to the hash in
+  file order. Give tasks id: tags to keep UIDs when descriptions change.
+
+OPTIONS:
+  --output FILE  Write to FILE instead of standard output
+
+EXAMPLES:
+  todotxt export ical > tasks.ics
+  todotxt export ical --output work.ics +Work
+  todotxt export ical all`,
+
+		"tui": `TUI COMMAND - Interactive task browser
+
+USAGE:
+  todotxt tui [filter]
+
+DESCRIPTION:
+  Opens a full-screen view of your tasks with a side pane of project and
+  context counts for the visible tasks. The filter works like list and
+  can be changed live with /. Changes are saved immediately and recorded
+  for undo-last. The file is only locked while a change is saved, and
+  changes made by other programs are picked up automatically.
+
+KEYS:
+  j, k, arrows   Move the selection
+  x              Mark the task done
+  u              Mark the task not done
+  +, -           Raise or lower the priority
+  p <letter>     Set the priority; p <space> removes it
+  e, Enter       Edit the task line
+  a              Add a task
+  d              Delete the task (asks first)
+  A              Archive completed tasks (asks first)
+  /              Edit the filter; Esc restores the previous one
+  r              Reload todo.txt
+  q              Quit
+
+EXAMPLES:
+  todotxt tui
+  todotxt tui +Work`,
+
+		"serve": `SERVE COMMAND - HTTP/JSON API
+
+USAGE:
+  todotxt serve [--addr ADDR]
+
+DESCRIPTION:
+  Serves the todo file over HTTP until interrupted: a web UI at / and a
+  JSON API under /api. The web UI lists tasks in a table that can be
+  sorted by clicking a column and filtered with the list syntax, with
+  checkboxes to complete tasks, menus to change priorities, and project
+  and context sidebars that filter on click.
+
+  Requests are handled
+  one at a time under the file lock, after reloading todo.txt, so edits
+  made by other programs show up immediately. Changes are recorded for
+  undo-last. Tasks use the schema of list --format json; request bodies
+  use the schema of import.
+
+  There is no authentication, so the server listens on 127.0.0.1 unless
+  --addr says otherwise. Requests that change tasks must send
+  Content-Type: application/json (415 otherwise), and browsers may only
+  send them from the web UI's own origin (403 otherwise).
+
+  Line numbers change when other programs add or remove lines. Add
+  ?expect=TEXT to a /api/tasks/{id} request, TEXT being the task's text
+  as last read, to get 409 instead of changing a different task.
+
+ENDPOINTS:
+  GET    /api/tasks?q=FILTER&sort=KEYS   List tasks (filter as in list)
+  POST   /api/tasks                      Add a task
+  GET    /api/tasks/{id}                 Get a task by line number or id:
+  PUT    /api/tasks/{id}                 Replace a task
+  PATCH  /api/tasks/{id}                 Set "priority" and/or "complete"
+  DELETE /api/tasks/{id}                 Delete a task
+  POST   /api/tasks/{id}/complete        Mark a task done
+  POST   /api/tasks/{id}/uncomplete      Mark a task not done
+  GET    /api/projects?all=true          Project counts
+  GET    /api/contexts?all=true          Context counts
+  POST   /api/archive                    Move completed tasks to done.txt
+
+OPTIONS:
+  --addr ADDR  Address to listen on (default 127.0.0.1:8080)
+
+EXAMPLES:
+  todotxt serve
+  todotxt serve --addr 127.0.0.1:9000
+  curl -H 'Content-Type: application/json' \
+       -d '{"text": "(A) Call Mom +Family"}' localhost:8080/api/tasks`,
+
+		"lsp": `LSP COMMAND - Language server for todo.txt files
+
+USAGE:
+  todotxt lsp
+
+DESCRIPTION:
+  Runs a Language Server Protocol server on standard input and output,
+  for editors that support LSP. Configure your editor to start
+  "todotxt lsp" for todo.txt files. It provides:
+
+  Diagnostics       The problems reported by lint, as you type
+  Completion        +projects, @contexts and tag keys from the open
+                    file and TODO_FILE
+  Code actions      Mark done, set or remove the priority, add a
+                    creation date, for every selected line
+  Hover             The due date relative to today and the task's age
+  Document symbols  Tasks grouped by project
+
+EXAMPLE:
+  todotxt lsp`,
+
+		"completion": `COMPLETION COMMAND - Shell completion scripts
+
+USAGE:
+  todotxt completion bash|zsh|fish
+
+DESCRIPTION:
+  Prints a completion script for the given shell. The script completes
+  command names and aliases, +projects and @contexts from TODO_FILE for
+  add, list and tui, and task IDs for do, undo, delete, priority and
+  depri, with each task's description shown as a hint.
+
+  Values are read from TODO_FILE each time you press Tab, so they follow
+  the file and the TODO_FILE set in your shell.
+
+EXAMPLES:
+  source <(todotxt completion bash)          # in ~/.bashrc
+  source <(todotxt completion zsh)           # in ~/.zshrc, after compinit
+  todotxt completion fish | source           # in config.fish
+  todotxt completion fish > ~/.config/fish/completions/todotxt.fish`,
+
+		"dates": `DATES - Absolute and relative dates
+
+USAGE:
+  todotxt add "Pay rent due:fri"
+  todotxt defer <ID> <date>
+  todotxt list 'due<=eow'
+
+DESCRIPTION:
+  Dates are YYYY-MM-DD, or relative to today:
+
+  today, tomorrow, yesterday
+  mon ... sun          The next such day after today (also monday ...)
+  next-mon ...         That day of next week; weeks start on Monday
+  next-week            Monday of next week
+  next-month           The first day of next month
+  next-year            January 1 of next year
+  eow, eom, eoy        The last day of this week (Sunday), month or year
+  +3d, -2w             An offset in days (d), weeks (w), months (m),
+                       years (y) or business days (b)
+
+  Words are case-insensitive. add, the tui and the web API replace
+  relative due: and t: dates with YYYY-MM-DD when a task is written, so
+  the task keeps its meaning. defer and filters accept the same dates.
+
+EXAMPLES:
+  todotxt add "Plan trip +Travel t:next-mon due:+2w"
+  todotxt list 'due<=eom and created>=-1w'`,
+
+		"config": `CONFIGURATION - Config file and profiles
+
+LOCATION:
+  $XDG_CONFIG_HOME/todotxt/config.toml, or ~/.config/todotxt/config.toml.
+  Set TODOTXT_CONFIG to use another file. A missing file is fine.
+
+SETTINGS:
+  todo_file            Path of todo.txt ("~/" is your home directory)
+  done_file            Path of done.txt
+  sort                 Default list --sort keys, e.g. "priority,due"
+  filter               Default list filter, e.g. "+Work or pri:A"
+  color                auto (default), always or never
+  auto_creation_date   true to date tasks when they are added
+  stable_ids           true to give new tasks an id: tag
+  timezone             IANA zone in which "today" starts, e.g.
+                       "Asia/Tokyo" (default: TZ or the system zone)
+
+  [colors] sets the colors of list output: done, overdue, priority_a,
+  priority_b, priority_c and priority (D-Z). A color is a list of
+  names (bold, dim, red, green, yellow, blue, magenta, cyan, white,
+  gray, bright-red, ...), SGR numbers such as "38;5;208", or "none".
+
+PROFILES:
+  A [profiles.NAME] table overrides any of the settings above, and
+  [profiles.NAME.colors] the colors. Select a profile with --profile
+  NAME, TODO_PROFILE, or profile = "NAME" at the top of the file.
+
+PRECEDENCE:
+  Flags win over environment variables, which win over the config file:
+  --sort and --color over the settings, and the todo_file and done_file
+  of a profile chosen with --profile over TODO_FILE and DONE_FILE.
+  TODO_FILE, DONE_FILE, TODO_STABLE_IDS, NO_COLOR and TZ win over the
+  settings of a profile selected by TODO_PROFILE or the config file.
+
+EXAMPLE:
+  sort = "priority,due"
+  auto_creation_date = true
+
+  [colors]
+  priority_a = "bold red"
+
+  [profiles.work]
+  todo_file = "~/work/todo.txt"
+  done_file = "~/work/done.txt"
+  filter = "+Work"`,
+
 		"delete": `DELETE COMMAND - Remove a task
 
 USAGE:
@@ -568,13 +1423,13 @@ USAGE:
   todotxt del <ID>
 
 DESCRIPTION:
-  Permanently removes a task from your todo.txt file. This action
-  cannot be undone.
+  Removes a task from your todo.txt file. <ID> is a line number or an
+  id: tag value. Run 'todotxt undo-last' to bring it back.
 
 EXAMPLES:
   todotxt delete 3
   todotxt rm 5
-  todotxt del 1`,
+  todotxt del k3m9qa`,
 
 		"undo": `UNDO COMMAND - Mark task as incomplete
 
@@ -602,6 +1457,89 @@ DESCRIPTION:
 EXAMPLES:
   todotxt depri 3
   todotxt depri 1`,
+
+		"defer": `DEFER COMMAND - Hide a task until a date
+
+USAGE:
+  todotxt defer <ID> <date>
+
+DESCRIPTION:
+  Sets the task's t: (threshold) tag. Until that date the task is
+  hidden from the default list, tui and web views and from project and
+  context counts; list --all-thresholds and filters still show it.
+
+  The date is YYYY-MM-DD or a relative date such as +3d, mon or
+  next-week (see help dates).
+
+EXAMPLES:
+  todotxt defer 3 2025-03-01
+  todotxt defer 3 +1w
+  todotxt defer 3 next-mon`,
+
+		"undo-last": `UNDO-LAST COMMAND - Revert the last change
+
+USAGE:
+  todotxt undo-last
+
+DESCRIPTION:
+  Every command that changes todo.txt or done.txt (add, do, undo, delete,
+  priority, depri, archive) is recorded in a journal next to the todo
+  file (<TODO_FILE>.journal), which keeps the changed lines of the last
+  100 changes. undo-last restores both files to how they were before the
+  most recent recorded change. Run it repeatedly to step further back. If
+  a file was edited since, nothing is changed.
+
+EXAMPLES:
+  todotxt rm 3
+  todotxt undo-last           # Task 3 is back`,
+
+		"redo": `REDO COMMAND - Re-apply a reverted change
+
+USAGE:
+  todotxt redo
+
+DESCRIPTION:
+  Re-applies the change most recently reverted by undo-last. Recording a
+  new change discards everything that could still be redone.
+
+EXAMPLE:
+  todotxt redo`,
+
+		"history": `HISTORY COMMAND - Show recorded changes
+
+USAGE:
+  todotxt history [N]
+
+DESCRIPTION:
+  Lists the last N (default 10) recorded changes, newest first. Changes
+  marked 'u' have been undone and can be redone.
+
+EXAMPLES:
+  todotxt history
+  todotxt history 50`,
+
+		"lint": `LINT COMMAND - Check a todo.txt file for problems
+
+USAGE:
+  todotxt lint [--format text|json] [file]
+
+DESCRIPTION:
+  Checks every line of a todo.txt file (default: TODO_FILE) and reports
+  problems with their line, column, severity and rule ID. Exits with a
+  non-zero status when any error is found, so it can gate pre-commit hooks.
+
+RULES:
+  invalid-date                 Creation or completion date is not a real date
+  lowercase-priority           Priority written as (a) instead of (A)
+  completed-with-priority      Completed task still has a priority
+  completion-before-creation   Completion date is earlier than creation date
+  invalid-tag-date             due: or t: value is not a YYYY-MM-DD date
+  invalid-recurrence           rec: value is not an interval like 1w or +1m
+
+EXAMPLES:
+  todotxt lint
+  todotxt lint ~/projects/app/todo.txt
+  todotxt lint --format json todo.txt`,
 	}
 
 	// Check for command aliases
@@ -628,37 +1566,73 @@ EXAMPLES:
 	return fmt.Errorf("no help available for command: %s", cmd)
 }
 
+// commandTable maps every command name and alias to its implementation.
+func commandTable() map[string]func([]string) error {
+	return map[string]func([]string) error{
+		"add":        addCommand,
+		"list":       listCommand,
+		"ls":         listCommand,
+		"agenda":     agendaCommand,
+		"do":         completeCommand,
+		"done":       completeCommand,
+		"complete":   completeCommand,
+		"undo":       uncompleteCommand,
+		"undone":     uncompleteCommand,
+		"delete":     deleteCommand,
+		"del":        deleteCommand,
+		"rm":         deleteCommand,
+		"priority":   priorityCommand,
+		"pri":        priorityCommand,
+		"depri":      depriCommand,
+		"defer":      deferCommand,
+		"projects":   projectsCommand,
+		"proj":       projectsCommand,
+		"contexts":   contextsCommand,
+		"ctx":        contextsCommand,
+		"archive":    archiveCommand,
+		"import":     importCommand,
+		"export":     exportCommand,
+		"tui":        tuiCommand,
+		"serve":      serveCommand,
+		"lsp":        lspCommand,
+		"completion": completionCommand,
+		"__complete": completeValuesCommand,
+		"lint":       lintCommand,
+		"undo-last":  undoLastCommand,
+		"redo":       redoCommand,
+		"history":    historyCommand,
+		"help":       helpCommand,
+	}
+}
+
 func executeCommand(name string, args []string) error {
-	commands := map[string]func([]string) error{
-		"add":      addCommand,
-		"list":     listCommand,
-		"ls":       listCommand,
-		"do":       completeCommand,
-		"done":     completeCommand,
-		"complete": completeCommand,
-		"undo":     uncompleteCommand,
-		"undone":   uncompleteCommand,
-		"delete":   deleteCommand,
-		"del":      deleteCommand,
-		"rm":       deleteCommand,
-		"priority": priorityCommand,
-		"pri":      priorityCommand,
-		"depri":    depriCommand,
-		"projects": projectsCommand,
-		"proj":     projectsCommand,
-		"contexts": contextsCommand,
-		"ctx":      contextsCommand,
-		"archive":  archiveCommand,
-		"help":     helpCommand,
-	}
-
-	if cmd, ok := commands[name]; ok {
+	if cmd, ok := commandTable()[name]; ok {
+		if mutatingCommands[name] {
+			return runJournaled(name, args, cmd)
+		}
 		return cmd(args)
 	}
 
 	return fmt.Errorf("unknown command: %s", name)
 }
 
+// parseFlags parses fs from args, allowing flags to appear before, between
+// or after positional arguments, and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
 func parseArgs() (string, []string) {
 	// Check for --help or -h before parsing flags
 	for _, arg := range os.Args[1:] {
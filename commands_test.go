@@ -0,0 +1,201 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// useTestTodoFile points the commands at a new todo file holding lines,
+// with done.txt next to it, for the rest of the test.
+func useTestTodoFile(t *testing.T, lines ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "todo.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DONE_FILE", filepath.Join(dir, "done.txt"))
+	t.Setenv("TODO_STABLE_IDS", "")
+
+	saved := todoFile
+	todoFile = todotxt.NewTodoFile(path)
+	t.Cleanup(func() { todoFile = saved })
+	if err := todoFile.Load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTodoFile(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(todoFile.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// captureStdout returns what fn prints to standard output.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	err = fn()
+	os.Stdout = saved
+	w.Close()
+	return string(<-done), err
+}
+
+func TestAddCommandRejectsBlankText(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "No arguments"},
+		{name: "Empty", args: []string{""}},
+		{name: "Spaces", args: []string{" "}},
+		{name: "Whitespace arguments", args: []string{"\t", " "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestTodoFile(t, "Call Mom")
+			if err := addCommand(tt.args); err == nil || err.Error() != "no task description provided" {
+				t.Errorf("addCommand(%q) error = %v", tt.args, err)
+			}
+			if got := readTodoFile(t); got != "Call Mom\n" {
+				t.Errorf("todo.txt = %q, want it unchanged", got)
+			}
+		})
+	}
+}
+
+func TestSelectTodosAfterAllOrDone(t *testing.T) {
+	useTestTodoFile(t,
+		"Write report +Work",
+		"x 2025-01-09 Send invoice +Work",
+		"x 2025-01-08 Water plants +Home",
+		"Call Mom",
+	)
+
+	tests := []struct {
+		args          []string
+		expected      []string
+		expectedError string
+	}{
+		{args: []string{"all"}, expected: []string{"Write report", "Send invoice", "Water plants", "Call Mom"}},
+		{args: []string{"all", "+Work"}, expected: []string{"Write report", "Send invoice"}},
+		{args: []string{"done"}, expected: []string{"Send invoice", "Water plants"}},
+		{args: []string{"done", "+Work"}, expected: []string{"Send invoice"}},
+		{args: []string{"done", "not", "+Work", "or", "+Work"}, expected: []string{"Send invoice", "Water plants"}},
+		{args: []string{"done", "+Work", "and"}, expectedError: "\n  done +Work and\n                ^"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			todos, err := selectTodos(tt.args, true)
+			if tt.expectedError != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.expectedError) {
+					t.Fatalf("selectTodos() error = %v, want suffix %q", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, todo := range todos {
+				got = append(got, todo.Description)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("selectTodos(%q) = %q, want %q", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommandsKeepBlankLines(t *testing.T) {
+	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+	useTestTodoFile(t, "Call Mom", "", "Pay rent", "Water plants")
+
+	if _, err := captureStdout(t, func() error { return priorityCommand([]string{"1", "A"}) }); err != nil {
+		t.Fatal(err)
+	}
+	// Each command runs in a new process, which loads the file again.
+	if err := todoFile.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := captureStdout(t, func() error { return completeCommand([]string{"3"}) }); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(A) Call Mom\n\nx 2025-01-10 Pay rent\nWater plants\n"
+	if got := readTodoFile(t); got != expected {
+		t.Errorf("todo.txt = %q, want %q", got, expected)
+	}
+}
+
+func TestImportCommandCollidingIDs(t *testing.T) {
+	rows := `{"text": "Pay rent id:k3m9qa"}` + "\n" +
+		`{"text": "Water plants id:w4t3rp"}` + "\n" +
+		`{"text": "Buy milk id:m1lkxx"}` + "\n"
+
+	tests := []struct {
+		name      string
+		stableIDs string
+	}{
+		{name: "Dropped", stableIDs: ""},
+		{name: "Replaced", stableIDs: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestTodoFile(t, "Call Mom id:k3m9qa")
+			t.Setenv("TODO_STABLE_IDS", tt.stableIDs)
+			if err := os.WriteFile(os.Getenv("DONE_FILE"), []byte("x 2025-01-09 Water plants id:w4t3rp\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			input := filepath.Join(t.TempDir(), "tasks.ndjson")
+			if err := os.WriteFile(input, []byte(rows), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := captureStdout(t, func() error { return importCommand([]string{input}) }); err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, todo := range todoFile.Todos {
+				ids = append(ids, todo.StableID())
+			}
+			expected := []string{"k3m9qa", "", "", "m1lkxx"}
+			if tt.stableIDs != "" {
+				expected = []string{"k3m9qa", ids[1], ids[2], "m1lkxx"}
+				for _, id := range ids[1:3] {
+					if id == "" || slices.Contains([]string{"k3m9qa", "w4t3rp", "m1lkxx"}, id) {
+						t.Errorf("Colliding id: should be replaced with a new one, got %q", id)
+					}
+				}
+			}
+			if !slices.Equal(ids, expected) {
+				t.Errorf("ids = %q, want %q", ids, expected)
+			}
+		})
+	}
+}
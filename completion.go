@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// The completion scripts list the commands statically and call back into
+// "todotxt __complete KIND" for values that depend on TODO_FILE.
+
+// Commands whose arguments complete to +project/@context values, or to
+// task IDs of open or completed tasks.
+const (
+	completionTaskCommands = "do done complete delete del rm priority pri depri defer"
+	completionDoneCommands = "undo undone"
+	completionTagCommands  = "add list ls agenda tui"
+)
+
+func completionCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: completion bash|zsh|fish")
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell: %s (want bash, zsh or fish)", args[0])
+	}
+
+	fmt.Print(strings.NewReplacer(
+		"%COMMANDS%", strings.Join(completionCommandNames(), " "),
+		"%TASK_COMMANDS%", completionTaskCommands,
+		"%DONE_COMMANDS%", completionDoneCommands,
+		"%TAG_COMMANDS%", completionTagCommands,
+	).Replace(script))
+	return nil
+}
+
+// completionCommandNames returns every command and alias except the hidden
+// ones, sorted.
+func completionCommandNames() []string {
+	var names []string
+	for name := range commandTable() {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeValuesCommand prints one completion candidate per line. Tasks are
+// printed as "ID<TAB>description".
+func completeValuesCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: __complete projects|contexts|tasks|done-tasks")
+	}
+
+	switch args[0] {
+	case "projects":
+		printNames("+", countNames(todoFile.Todos, func(todo *todotxt.Todo) []string { return todo.Projects }))
+	case "contexts":
+		printNames("@", countNames(todoFile.Todos, func(todo *todotxt.Todo) []string { return todo.Contexts }))
+	case "tasks":
+		printTaskHints(todoFile.GetIncomplete())
+	case "done-tasks":
+		printTaskHints(todoFile.GetCompleted())
+	default:
+		return fmt.Errorf("unknown completion kind: %s", args[0])
+	}
+	return nil
+}
+
+func printNames(prefix string, counts map[string]int) {
+	for _, row := range tallyRows(counts) {
+		if row.Name != "" {
+			fmt.Println(prefix + row.Name)
+		}
+	}
+}
+
+func printTaskHints(todos []*todotxt.Todo) {
+	for _, todo := range todos {
+		description := strings.Join(strings.Fields(todo.Description), " ")
+		if todo.Priority != todotxt.PriorityNone {
+			description = fmt.Sprintf("(%c) %s", todo.Priority, description)
+		}
+		fmt.Printf("%d\t%s\n", todo.ID, description)
+	}
+}
+
+const bashCompletion = `# bash completion for todotxt
+# Load with: source <(todotxt completion bash)
+
+_todotxt_tasks() {
+    local line id width=0
+    local -a ids=() hints=()
+    while IFS= read -r line; do
+        id=${line%%$'\t'*}
+        [[ $id == "$cur"* ]] || continue
+        ids+=("$id")
+        hints+=("${line#*$'\t'}")
+        ((${#id} > width)) && width=${#id}
+    done < <("$prog" __complete "$1" 2>/dev/null)
+
+    # A single match is inserted as is; several are shown with their
+    # descriptions, which never share a prefix beyond the ID.
+    if ((${#ids[@]} == 1)); then
+        COMPREPLY=("${ids[0]}")
+        return
+    fi
+    local i
+    for i in "${!ids[@]}"; do
+        printf -v line '%-*s  -- %s' "$width" "${ids[i]}" "${hints[i]}"
+        COMPREPLY+=("$line")
+    done
+}
+
+_todotxt() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prog=${COMP_WORDS[0]}
+    local cmd= arg=0 i
+    COMPREPLY=()
+
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+        --format | --profile | --color) ((i++)) ;;
+        -*) ;;
+        *)
+            cmd=${COMP_WORDS[i]}
+            arg=$((COMP_CWORD - i))
+            break
+            ;;
+        esac
+    done
+
+    case ${COMP_WORDS[COMP_CWORD - 1]} in
+    --format)
+        COMPREPLY=($(compgen -W "text json ndjson csv tsv" -- "$cur"))
+        return
+        ;;
+    --color)
+        COMPREPLY=($(compgen -W "auto always never" -- "$cur"))
+        return
+        ;;
+    --profile) return ;;
+    esac
+
+    if [[ -z $cmd ]]; then
+        COMPREPLY=($(compgen -W "%COMMANDS%" -- "$cur"))
+        return
+    fi
+
+    case " %TAG_COMMANDS% " in *" $cmd "*)
+        case $cur in
+        +*) COMPREPLY=($(compgen -W "$("$prog" __complete projects 2>/dev/null)" -- "$cur")) ;;
+        @*) COMPREPLY=($(compgen -W "$("$prog" __complete contexts 2>/dev/null)" -- "$cur")) ;;
+        esac
+        return
+        ;;
+    esac
+    case " %TASK_COMMANDS% " in *" $cmd "*)
+        ((arg == 1)) && _todotxt_tasks tasks
+        return
+        ;;
+    esac
+    case " %DONE_COMMANDS% " in *" $cmd "*)
+        ((arg == 1)) && _todotxt_tasks done-tasks
+        return
+        ;;
+    esac
+
+    case $cmd in
+    help)
+        ((arg == 1)) && COMPREPLY=($(compgen -W "%COMMANDS%" -- "$cur"))
+        ;;
+    completion)
+        ((arg == 1)) && COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+        ;;
+    import | lint)
+        COMPREPLY=($(compgen -f -- "$cur"))
+        ;;
+    esac
+}
+
+complete -F _todotxt todotxt
+`
+
+const zshCompletion = `#compdef todotxt
+# zsh completion for todotxt
+# Load with: source <(todotxt completion zsh)
+# or save as _todotxt in a directory on $fpath.
+
+_todotxt_values() {
+    local -a values
+    values=(${(f)"$(${words[1]} __complete $1 2>/dev/null)"})
+    compadd -a values
+}
+
+_todotxt_tasks() {
+    local -a tasks
+    tasks=(${(f)"$(${words[1]} __complete $1 2>/dev/null)"})
+    tasks=(${tasks/$'\t'/:})
+    _describe -V -t tasks 'task' tasks
+}
+
+_todotxt() {
+    local cmd arg=0 i
+    local -a tag_commands=(%TAG_COMMANDS%)
+    local -a task_commands=(%TASK_COMMANDS%)
+    local -a done_commands=(%DONE_COMMANDS%)
+
+    for ((i = 2; i < CURRENT; i++)); do
+        case ${words[i]} in
+        --format | --profile | --color) ((i++)) ;;
+        -*) ;;
+        *)
+            cmd=${words[i]}
+            arg=$((CURRENT - i))
+            break
+            ;;
+        esac
+    done
+
+    case ${words[CURRENT-1]} in
+    --format) compadd text json ndjson csv tsv; return ;;
+    --color) compadd auto always never; return ;;
+    --profile) return ;;
+    esac
+
+    if [[ -z $cmd ]]; then
+        compadd %COMMANDS%
+        return
+    fi
+
+    if (( $tag_commands[(Ie)$cmd] )); then
+        case $PREFIX in
+        +*) _todotxt_values projects ;;
+        @*) _todotxt_values contexts ;;
+        esac
+    elif (( $task_commands[(Ie)$cmd] )); then
+        ((arg == 1)) && _todotxt_tasks tasks
+    elif (( $done_commands[(Ie)$cmd] )); then
+        ((arg == 1)) && _todotxt_tasks done-tasks
+    else
+        case $cmd in
+        help) ((arg == 1)) && compadd %COMMANDS% ;;
+        completion) ((arg == 1)) && compadd bash zsh fish ;;
+        import|lint) _files ;;
+        esac
+    fi
+}
+
+if [[ $funcstack[1] == _todotxt ]]; then
+    _todotxt "$@"
+else
+    compdef _todotxt todotxt
+fi
+`
+
+const fishCompletion = `# fish completion for todotxt
+# Load with: todotxt completion fish | source
+
+# __todotxt_args prints the command and its arguments typed so far,
+# skipping the program name and global flags.
+function __todotxt_args
+    set -l skip 1
+    set -l found 0
+    for token in (commandline -opc)
+        if test $skip -gt 0
+            set skip (math $skip - 1)
+        else if test $found = 1
+            echo $token
+        else if contains -- $token --format --profile --color
+            set skip 1
+        else if not string match -q -- '-*' $token
+            set found 1
+            echo $token
+        end
+    end
+end
+
+# __todotxt_first_arg succeeds when completing the first argument of one
+# of the given commands.
+function __todotxt_first_arg
+    set -l args (__todotxt_args)
+    test (count $args) -eq 1; and contains -- $args[1] $argv
+end
+
+function __todotxt_values
+    set -l prog (commandline -opc)[1]
+    switch (commandline -ct)
+        case '+*'
+            $prog __complete projects 2>/dev/null
+        case '@*'
+            $prog __complete contexts 2>/dev/null
+    end
+end
+
+function __todotxt_complete
+    set -l prog (commandline -opc)[1]
+    $prog __complete $argv 2>/dev/null
+end
+
+complete -c todotxt -f
+complete -c todotxt -l format -x -a 'text json ndjson csv tsv' -d 'Output format'
+complete -c todotxt -l profile -x -d 'Config profile'
+complete -c todotxt -l color -x -a 'auto always never' -d 'Colorize output'
+complete -c todotxt -n 'test (count (__todotxt_args)) -eq 0' -a '%COMMANDS%'
+complete -c todotxt -n '__fish_seen_subcommand_from %TAG_COMMANDS%' -a '(__todotxt_values)'
+complete -c todotxt -n '__todotxt_first_arg %TASK_COMMANDS%' -k -a '(__todotxt_complete tasks)'
+complete -c todotxt -n '__todotxt_first_arg %DONE_COMMANDS%' -k -a '(__todotxt_complete done-tasks)'
+complete -c todotxt -n '__todotxt_first_arg help' -a '%COMMANDS%'
+complete -c todotxt -n '__todotxt_first_arg completion' -a 'bash zsh fish'
+complete -c todotxt -n '__fish_seen_subcommand_from import lint' -F
+`
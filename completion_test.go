@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCompleteValuesCommand(t *testing.T) {
+	tests := []struct {
+		kind     string
+		expected string
+	}{
+		{kind: "projects", expected: "+Family\n+Home\n+Work\n"},
+		{kind: "contexts", expected: "@phone\n"},
+		{kind: "tasks", expected: "1\t(B) Call Mom\n2\tPay rent\n4\tWrite report\n"},
+		{kind: "done-tasks", expected: "3\tWater plants\n"},
+	}
+
+	useTestTodoFile(t,
+		"(B) Call Mom +Family @phone",
+		"Pay  rent +Home due:2025-01-09",
+		"x 2025-01-05 Water plants +Home",
+		"Write report +Work",
+	)
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			out, err := captureStdout(t, func() error { return completeValuesCommand([]string{tt.kind}) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.expected {
+				t.Errorf("__complete %s = %q, want %q", tt.kind, out, tt.expected)
+			}
+		})
+	}
+
+	if _, err := captureStdout(t, func() error { return completeValuesCommand([]string{"tags"}) }); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			out, err := captureStdout(t, func() error { return completionCommand([]string{shell}) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(out, "_COMMANDS%") {
+				t.Error("Script has unreplaced placeholders")
+			}
+			for _, name := range []string{"agenda", "archive", "undo-last", "completion"} {
+				if !strings.Contains(out, name) {
+					t.Errorf("Script should complete the %s command", name)
+				}
+			}
+			if !strings.Contains(out, "__complete ") {
+				t.Error("Script should call back into __complete")
+			}
+
+			// Check the syntax with the shell itself where it is installed.
+			if path, err := exec.LookPath(shell); err == nil {
+				cmd := exec.Command(path, "-n")
+				cmd.Stdin = strings.NewReader(out)
+				if output, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("%s -n failed: %v\n%s", shell, err, output)
+				}
+			}
+		})
+	}
+
+	if _, err := captureStdout(t, func() error { return completionCommand([]string{"tcsh"}) }); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// Options are taken from flags first, then environment variables, then the
+// config file of the selected profile.
+var (
+	profileFlag = flag.String("profile", "", "config profile to use")
+	colorFlag   = flag.String("color", "", "colorize output: auto, always or never")
+)
+
+// settings holds the config file settings of the selected profile.
+var settings Settings
+
+// profilePaths holds the todo_file and done_file of a profile selected with
+// --profile. Like other flags they win over TODO_FILE and DONE_FILE.
+var profilePaths Settings
+
+// colorOutput reports whether list output is colorized, and palette maps
+// the keys of colorKeys to SGR parameters.
+var (
+	colorOutput bool
+	palette     map[string]string
+)
+
+var defaultColors = map[string]string{
+	"done":       "gray",
+	"overdue":    "red",
+	"priority_a": "bold yellow",
+	"priority_b": "green",
+	"priority_c": "bright-blue",
+	"priority":   "none",
+}
+
+var colorNames = map[string]string{
+	"bold": "1", "dim": "2", "italic": "3", "underline": "4", "reverse": "7",
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37", "gray": "90",
+	"bright-red": "91", "bright-green": "92", "bright-yellow": "93",
+	"bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+}
+
+func configPath() string {
+	if path := os.Getenv("TODOTXT_CONFIG"); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		homeDir, _ := os.UserHomeDir()
+		dir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(dir, "todotxt", "config.toml")
+}
+
+func initSettings() {
+	if err := loadSettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func loadSettings() error {
+	config, err := loadConfig(configPath())
+	if err != nil {
+		return err
+	}
+
+	profile := *profileFlag
+	if profile == "" {
+		profile = os.Getenv("TODO_PROFILE")
+	}
+	settings, err = config.Resolve(profile)
+	if err != nil {
+		return err
+	}
+	if *profileFlag != "" {
+		selected := config.Profiles[*profileFlag]
+		profilePaths = Settings{TodoFile: selected.TodoFile, DoneFile: selected.DoneFile}
+	}
+
+	// Go reads TZ into time.Local; the config only applies when it is unset.
+	if _, ok := os.LookupEnv("TZ"); !ok && settings.Timezone != "" {
+		loc, err := time.LoadLocation(settings.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", settings.Timezone, err)
+		}
+		todotxt.SetClock(todotxt.SystemClock(loc))
+	}
+
+	return initColors()
+}
+
+func initColors() error {
+	mode := *colorFlag
+	if mode == "" && os.Getenv("NO_COLOR") != "" {
+		mode = "never"
+	}
+	if mode == "" {
+		mode = settings.Color
+	}
+	switch mode {
+	case "", "auto":
+		info, err := os.Stdout.Stat()
+		colorOutput = err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+	case "always":
+		colorOutput = true
+	case "never":
+		colorOutput = false
+	default:
+		return fmt.Errorf("invalid --color %q (want auto, always or never)", mode)
+	}
+
+	palette = make(map[string]string)
+	for _, key := range colorKeys {
+		spec, ok := settings.Colors[key]
+		if !ok {
+			spec = defaultColors[key]
+		}
+		code, err := colorCode(spec)
+		if err != nil {
+			return fmt.Errorf("invalid color for %s: %w", key, err)
+		}
+		palette[key] = code
+	}
+	return nil
+}
+
+// colorCode converts a color such as "bold red", "none" or "1;38;5;208"
+// to SGR parameters.
+func colorCode(spec string) (string, error) {
+	var codes []string
+	for _, word := range strings.Fields(spec) {
+		if word == "none" {
+			continue
+		}
+		if code, ok := colorNames[word]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		for _, part := range strings.Split(word, ";") {
+			if _, err := strconv.Atoi(part); err != nil {
+				return "", fmt.Errorf("unknown color %q", word)
+			}
+		}
+		codes = append(codes, word)
+	}
+	return strings.Join(codes, ";"), nil
+}
+
+// todoColor returns the SGR parameters for a task in list output.
+func todoColor(todo *todotxt.Todo) string {
+	switch {
+	case todo.Complete:
+		return palette["done"]
+	case todotxt.IsOverdue(todotxt.Now())(todo):
+		return palette["overdue"]
+	case todo.Priority == 'A':
+		return palette["priority_a"]
+	case todo.Priority == 'B':
+		return palette["priority_b"]
+	case todo.Priority == 'C':
+		return palette["priority_c"]
+	case todo.Priority != todotxt.PriorityNone:
+		return palette["priority"]
+	}
+	return ""
+}
+
+func colorize(code, s string) string {
+	if !colorOutput || code == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// configuredPath returns the path of the profile selected with --profile,
+// the value of the environment variable env, the config file's path, or
+// the file name in the home directory, in that order. Configured paths are
+// expanded from "~/".
+func configuredPath(env, flagged, configured, name string) string {
+	if flagged != "" {
+		configured = flagged
+	} else if path := os.Getenv(env); path != "" {
+		return path
+	}
+	homeDir, _ := os.UserHomeDir()
+	if configured == "" {
+		return filepath.Join(homeDir, name)
+	}
+	if rest, ok := strings.CutPrefix(configured, "~/"); ok {
+		return filepath.Join(homeDir, rest)
+	}
+	return configured
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfiguredPathPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		name       string
+		env        string
+		flagged    string
+		configured string
+		expected   string
+	}{
+		{name: "Default", expected: filepath.Join(home, "todo.txt")},
+		{name: "Config file", configured: "~/tasks/todo.txt", expected: filepath.Join(home, "tasks/todo.txt")},
+		{name: "Environment over config file", env: "/env/todo.txt", configured: "/config/todo.txt", expected: "/env/todo.txt"},
+		{name: "Flagged profile over environment", env: "/env/todo.txt", flagged: "~/work/todo.txt", configured: "/config/todo.txt", expected: filepath.Join(home, "work/todo.txt")},
+		{name: "Environment when the profile sets no path", env: "/env/todo.txt", configured: "/config/todo.txt", expected: "/env/todo.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TODO_FILE", tt.env)
+			if got := configuredPath("TODO_FILE", tt.flagged, tt.configured, "todo.txt"); got != tt.expected {
+				t.Errorf("configuredPath() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsProfilePaths(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	config := "todo_file = \"/config/todo.txt\"\n" +
+		"profile = \"home\"\n" +
+		"[profiles.home]\n" +
+		"done_file = \"/home/done.txt\"\n" +
+		"[profiles.work]\n" +
+		"todo_file = \"/work/todo.txt\"\n"
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TODOTXT_CONFIG", configFile)
+	t.Setenv("TODO_PROFILE", "")
+	t.Setenv("TODO_FILE", "/env/todo.txt")
+	t.Setenv("DONE_FILE", "/env/done.txt")
+	defer func(flag string) {
+		*profileFlag = flag
+		settings, profilePaths = Settings{}, Settings{}
+	}(*profileFlag)
+
+	tests := []struct {
+		profile      string
+		expectedTodo string
+		expectedDone string
+	}{
+		{profile: "", expectedTodo: "/env/todo.txt", expectedDone: "/env/done.txt"},
+		{profile: "home", expectedTodo: "/env/todo.txt", expectedDone: "/home/done.txt"},
+		{profile: "work", expectedTodo: "/work/todo.txt", expectedDone: "/env/done.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run("profile "+tt.profile, func(t *testing.T) {
+			*profileFlag = tt.profile
+			profilePaths = Settings{}
+			if err := loadSettings(); err != nil {
+				t.Fatal(err)
+			}
+			if got := todoPath(); got != tt.expectedTodo {
+				t.Errorf("todo file = %q, want %q", got, tt.expectedTodo)
+			}
+			if got := donePath(); got != tt.expectedDone {
+				t.Errorf("done file = %q, want %q", got, tt.expectedDone)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// mutatingCommands are recorded in the journal so they can be undone with
+// undo-last.
+var mutatingCommands = map[string]bool{
+	"add":      true,
+	"do":       true,
+	"done":     true,
+	"complete": true,
+	"undo":     true,
+	"undone":   true,
+	"delete":   true,
+	"del":      true,
+	"rm":       true,
+	"priority": true,
+	"pri":      true,
+	"depri":    true,
+	"defer":    true,
+	"archive":  true,
+	"import":   true,
+}
+
+func journalPath() string {
+	return todoFile.Path + ".journal"
+}
+
+// runJournaled runs a mutating command and records how it changed the todo
+// and done files.
+func runJournaled(name string, args []string, cmd func([]string) error) error {
+	paths := []string{todoFile.Path, donePath()}
+
+	before := make([]*string, len(paths))
+	for i, path := range paths {
+		content, err := todotxt.ReadFileContent(path)
+		if err != nil {
+			return err
+		}
+		before[i] = content
+	}
+
+	if err := cmd(args); err != nil {
+		return err
+	}
+
+	var changes []todotxt.FileChange
+	for i, path := range paths {
+		after, err := todotxt.ReadFileContent(path)
+		if err != nil {
+			return err
+		}
+		if !sameContent(before[i], after) {
+			changes = append(changes, todotxt.NewFileChange(path, before[i], after))
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	op := todotxt.Operation{
+		Time:    todotxt.Now(),
+		Command: strings.Join(append([]string{name}, args...), " "),
+		Changes: changes,
+	}
+	if err := todotxt.NewJournal(journalPath()).Record(op); err != nil {
+		return fmt.Errorf("failed to record journal: %w", err)
+	}
+	return nil
+}
+
+func sameContent(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// lockDoneFile locks done.txt, which undo-last and redo may rewrite.
+func lockDoneFile() (*todotxt.TodoFile, error) {
+	doneFile := todotxt.NewTodoFile(donePath())
+	if err := doneFile.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock archive file: %w", err)
+	}
+	return doneFile, nil
+}
+
+func undoLastCommand(args []string) error {
+	doneFile, err := lockDoneFile()
+	if err != nil {
+		return err
+	}
+	defer doneFile.Unlock()
+
+	op, err := todotxt.NewJournal(journalPath()).Undo()
+	if err != nil {
+		return fmt.Errorf("failed to undo: %w", err)
+	}
+
+	fmt.Printf("Undid: %s\n", op.Command)
+	return nil
+}
+
+func redoCommand(args []string) error {
+	doneFile, err := lockDoneFile()
+	if err != nil {
+		return err
+	}
+	defer doneFile.Unlock()
+
+	op, err := todotxt.NewJournal(journalPath()).Redo()
+	if err != nil {
+		return fmt.Errorf("failed to redo: %w", err)
+	}
+
+	fmt.Printf("Redid: %s\n", op.Command)
+	return nil
+}
+
+func historyCommand(args []string) error {
+	limit := 10
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid count: %s", args[0])
+		}
+		limit = n
+	}
+
+	ops, cursor, err := todotxt.NewJournal(journalPath()).History()
+	if err != nil {
+		return err
+	}
+
+	if len(ops) == 0 {
+		fmt.Println("No history.")
+		return nil
+	}
+
+	start := 0
+	if len(ops) > limit {
+		start = len(ops) - limit
+	}
+
+	for i := len(ops) - 1; i >= start; i-- {
+		marker := " "
+		if i >= cursor {
+			marker = "u"
+		}
+		fmt.Printf("[%s] %3d: %s  %s\n", marker, i+1, ops[i].Time.In(todotxt.Now().Location()).Format("2006-01-02 15:04:05"), ops[i].Command)
+	}
+
+	return nil
+}
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// The lsp command is a Language Server Protocol server for todo.txt files
+// speaking JSON-RPC over standard input and output. Documents are synced
+// in full; positions are converted between the protocol's UTF-16 columns
+// and byte offsets.
+
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+
+	lspCompletionProperty = 10
+	lspCompletionModule   = 9
+	lspCompletionFolder   = 19
+
+	lspSymbolNamespace = 3
+	lspSymbolEvent     = 24
+
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+type lspRequest struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+type lspResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  any              `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   lspError         `json:"error"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label    string      `json:"label"`
+	Kind     int         `json:"kind"`
+	Detail   string      `json:"detail,omitempty"`
+	TextEdit lspTextEdit `json:"textEdit"`
+}
+
+type lspCodeAction struct {
+	Title string           `json:"title"`
+	Kind  string           `json:"kind"`
+	Edit  lspWorkspaceEdit `json:"edit"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// lspServer holds the open documents, keyed by URI, as lines.
+type lspServer struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string][]string
+}
+
+func lspCommand(args []string) error {
+	// Editors run the server for a whole session; do not hold the lock.
+	todoFile.Unlock()
+
+	s := &lspServer{
+		in:   bufio.NewReader(os.Stdin),
+		out:  os.Stdout,
+		docs: make(map[string][]string),
+	}
+	return s.run()
+}
+
+func (s *lspServer) run() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req lspRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		if rpcErr != nil {
+			err = s.write(lspErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: *rpcErr})
+		} else {
+			err = s.write(lspResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// read returns the body of the next message, framed by a Content-Length
+// header.
+func (s *lspServer) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	return body, nil
+}
+
+func (s *lspServer) write(msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *lspServer) notify(method string, params any) error {
+	return s.write(lspNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *lspServer) handle(req lspRequest) (any, *lspError) {
+	decode := func(v any) *lspError {
+		if err := json.Unmarshal(req.Params, v); err != nil {
+			return &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":       1,
+				"completionProvider":     map[string]any{"triggerCharacters": []string{"+", "@"}},
+				"hoverProvider":          true,
+				"codeActionProvider":     true,
+				"documentSymbolProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "todotxt"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		s.open(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.open(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params lspTextDocumentPosition
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/completion":
+		var params lspTextDocumentPosition
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params lspTextDocumentPosition
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/codeAction":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			Range lspRange `json:"range"`
+		}
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.codeActions(params.TextDocument.URI, params.Range), nil
+	case "textDocument/documentSymbol":
+		var params lspTextDocumentPosition
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.symbols(params.TextDocument.URI), nil
+	}
+
+	if strings.HasPrefix(req.Method, "$/") || req.ID == nil {
+		return nil, nil
+	}
+	return nil, &lspError{Code: lspMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// open stores a document and publishes its lint diagnostics.
+func (s *lspServer) open(uri, text string) {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	s.docs[uri] = lines
+
+	diagnostics := []lspDiagnostic{}
+	for i, line := range lines {
+		for _, d := range todotxt.LintLine(line, i+1) {
+			start := d.Column - 1
+			end := start
+			for end < len(line) && line[end] != ' ' && line[end] != '\t' {
+				end++
+			}
+			severity := lspSeverityWarning
+			if d.Severity == todotxt.SeverityError {
+				severity = lspSeverityError
+			}
+			diagnostics = append(diagnostics, lspDiagnostic{
+				Range:    lineRange(i, line, start, end),
+				Severity: severity,
+				Code:     d.Rule,
+				Source:   "todotxt",
+				Message:  d.Message,
+			})
+		}
+	}
+
+	s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+func (s *lspServer) line(uri string, n int) (string, bool) {
+	lines := s.docs[uri]
+	if n < 0 || n >= len(lines) {
+		return "", false
+	}
+	return lines[n], true
+}
+
+// completion offers projects after +, contexts after @, and tag keys for
+// other words, taken from the document and the todo file.
+func (s *lspServer) completion(params lspTextDocumentPosition) []lspCompletionItem {
+	items := []lspCompletionItem{}
+	line, ok := s.line(params.TextDocument.URI, params.Position.Line)
+	if !ok {
+		return items
+	}
+
+	end := byteOffset(line, params.Position.Character)
+	start := end
+	for start > 0 && line[start-1] != ' ' && line[start-1] != '\t' {
+		start--
+	}
+	word := line[start:end]
+	if word == "" {
+		return items
+	}
+
+	todos := s.todos(params.TextDocument.URI)
+	edit := lineRange(params.Position.Line, line, start, end)
+	add := func(label, detail string, kind int) {
+		if label != word {
+			items = append(items, lspCompletionItem{
+				Label:    label,
+				Kind:     kind,
+				Detail:   detail,
+				TextEdit: lspTextEdit{Range: edit, NewText: label},
+			})
+		}
+	}
+
+	switch word[0] {
+	case '+':
+		counts := countNames(todos, func(todo *todotxt.Todo) []string { return todo.Projects })
+		for _, row := range tallyRows(counts) {
+			if row.Name != "" && strings.HasPrefix(row.Name, word[1:]) {
+				add("+"+row.Name, fmt.Sprintf("%d task(s)", row.Count), lspCompletionModule)
+			}
+		}
+	case '@':
+		counts := countNames(todos, func(todo *todotxt.Todo) []string { return todo.Contexts })
+		for _, row := range tallyRows(counts) {
+			if row.Name != "" && strings.HasPrefix(row.Name, word[1:]) {
+				add("@"+row.Name, fmt.Sprintf("%d task(s)", row.Count), lspCompletionFolder)
+			}
+		}
+	default:
+		if strings.Contains(word, ":") {
+			return items
+		}
+		keys := map[string]bool{todotxt.IDTag: true, "rec": true}
+		for _, key := range todotxt.DateTags {
+			keys[key] = true
+		}
+		for _, todo := range todos {
+			for key := range todo.Tags {
+				keys[key] = true
+			}
+		}
+		var sorted []string
+		for key := range keys {
+			if strings.HasPrefix(key, word) {
+				sorted = append(sorted, key)
+			}
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			add(key+":", "tag", lspCompletionProperty)
+		}
+	}
+	return items
+}
+
+// todos parses the document and the todo file for completion candidates.
+func (s *lspServer) todos(uri string) []*todotxt.Todo {
+	todos, _ := todotxt.ParseTodos(s.docs[uri])
+	return append(todos, todoFile.Todos...)
+}
+
+// hover shows a task's due date and age.
+func (s *lspServer) hover(params lspTextDocumentPosition) any {
+	line, ok := s.line(params.TextDocument.URI, params.Position.Line)
+	if !ok {
+		return nil
+	}
+	todo, _ := todotxt.ParseTodo(line)
+	if todo == nil {
+		return nil
+	}
+
+	today := todotxt.Now()
+	var parts []string
+	if due := todo.GetDueDate(); due != nil {
+		label := relativeDays(daysBetween(today, *due))
+		if !todo.Complete && daysBetween(today, *due) < 0 {
+			label = "overdue, " + label
+		}
+		parts = append(parts, fmt.Sprintf("**Due** %s (%s)", due.Format("2006-01-02"), label))
+	}
+	if todo.CreationDate != nil {
+		end := today
+		if todo.Complete && todo.CompletionDate != nil {
+			end = *todo.CompletionDate
+		}
+		age := daysBetween(*todo.CreationDate, end)
+		parts = append(parts, fmt.Sprintf("**Age** %d day(s), created %s", age, todo.CreationDate.Format("2006-01-02")))
+	}
+	if todo.Complete && todo.CompletionDate != nil {
+		parts = append(parts, fmt.Sprintf("**Completed** %s", todo.CompletionDate.Format("2006-01-02")))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	return map[string]any{
+		"contents": map[string]string{"kind": "markdown", "value": strings.Join(parts, "  \n")},
+		"range":    lineRange(params.Position.Line, line, 0, len(line)),
+	}
+}
+
+// codeActions offers to mark the selected tasks done, set or remove their
+// priority, and add a creation date.
+func (s *lspServer) codeActions(uri string, r lspRange) []lspCodeAction {
+	actions := []lspCodeAction{}
+
+	last := r.End.Line
+	if last > r.Start.Line && r.End.Character == 0 {
+		last--
+	}
+
+	type target struct {
+		n    int
+		line string
+		todo *todotxt.Todo
+	}
+	var targets []target
+	for n := r.Start.Line; n <= last; n++ {
+		line, ok := s.line(uri, n)
+		if !ok {
+			break
+		}
+		if todo, _ := todotxt.ParseTodo(line); todo != nil {
+			targets = append(targets, target{n, line, todo})
+		}
+	}
+
+	// apply returns the text that replaces a task's line.
+	action := func(title string, apply func(*todotxt.Todo) (string, bool)) {
+		var edits []lspTextEdit
+		for _, t := range targets {
+			todo, _ := todotxt.ParseTodo(t.line)
+			if text, ok := apply(todo); ok {
+				edits = append(edits, lspTextEdit{
+					Range:   lineRange(t.n, t.line, 0, len(t.line)),
+					NewText: text,
+				})
+			}
+		}
+		if len(edits) == 0 {
+			return
+		}
+		if len(targets) > 1 {
+			title = fmt.Sprintf("%s (%d tasks)", title, len(edits))
+		}
+		actions = append(actions, lspCodeAction{
+			Title: title,
+			Kind:  "refactor.rewrite",
+			Edit:  lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: edits}},
+		})
+	}
+
+	// A recurring task is followed by its next occurrence; one with an
+	// invalid rec: tag is left to the diagnostic.
+	action("Mark done", func(todo *todotxt.Todo) (string, bool) {
+		if todo.Complete {
+			return "", false
+		}
+		next, err := todo.Recur(todotxt.Now())
+		if err != nil {
+			return "", false
+		}
+		todo.MarkComplete()
+		if next != nil {
+			return todo.String() + "\n" + next.String(), true
+		}
+		return todo.String(), true
+	})
+	for _, p := range []todotxt.Priority{'A', 'B', 'C'} {
+		action(fmt.Sprintf("Set priority %c", p), func(todo *todotxt.Todo) (string, bool) {
+			if todo.Complete || todo.Priority == p {
+				return "", false
+			}
+			todo.SetPriority(p)
+			return todo.String(), true
+		})
+	}
+	action("Remove priority", func(todo *todotxt.Todo) (string, bool) {
+		if todo.Priority == todotxt.PriorityNone {
+			return "", false
+		}
+		todo.SetPriority(todotxt.PriorityNone)
+		return todo.String(), true
+	})
+	action("Add creation date", func(todo *todotxt.Todo) (string, bool) {
+		// Without a completion date, a date after x is read as one.
+		if todo.CreationDate != nil || todo.Complete && todo.CompletionDate == nil {
+			return "", false
+		}
+		today := todotxt.Today()
+		todo.CreationDate = &today
+		return todo.String(), true
+	})
+
+	return actions
+}
+
+// symbols lists the tasks grouped by project as list --group project
+// groups them; tasks in several projects appear under each.
+func (s *lspServer) symbols(uri string) []lspDocumentSymbol {
+	lines := s.docs[uri]
+	todos, _ := todotxt.ParseTodos(lines)
+	groups, _ := todotxt.GroupTodos(todos, "project")
+
+	symbols := []lspDocumentSymbol{}
+	for _, g := range groups {
+		group := lspDocumentSymbol{Name: g.Label, Detail: fmt.Sprintf("%d task(s)", len(g.Todos)), Kind: lspSymbolNamespace}
+		for _, todo := range g.Todos {
+			// ParseTodos numbers tasks by line.
+			n := todo.ID - 1
+			name := todo.Description
+			if name == "" {
+				name = strings.TrimSpace(lines[n])
+			}
+			detail := ""
+			if todo.Complete {
+				detail = "done"
+			} else if todo.Priority != todotxt.PriorityNone {
+				detail = fmt.Sprintf("(%c)", todo.Priority)
+			}
+			whole := lineRange(n, lines[n], 0, len(lines[n]))
+			group.Children = append(group.Children, lspDocumentSymbol{Name: name, Detail: detail, Kind: lspSymbolEvent, Range: whole, SelectionRange: whole})
+		}
+		first, last := group.Children[0], group.Children[len(group.Children)-1]
+		group.Range = lspRange{Start: first.Range.Start, End: last.Range.End}
+		group.SelectionRange = first.Range
+		symbols = append(symbols, group)
+	}
+	return symbols
+}
+
+// lineRange converts byte offsets start and end on line n to a protocol
+// range.
+func lineRange(n int, line string, start, end int) lspRange {
+	return lspRange{
+		Start: lspPosition{Line: n, Character: utf16Column(line, start)},
+		End:   lspPosition{Line: n, Character: utf16Column(line, end)},
+	}
+}
+
+// utf16Column converts a byte offset in line to UTF-16 code units.
+func utf16Column(line string, offset int) int {
+	column := 0
+	for _, r := range line[:min(offset, len(line))] {
+		column += utf16.RuneLen(r)
+	}
+	return column
+}
+
+// byteOffset converts a UTF-16 column in line to a byte offset.
+func byteOffset(line string, column int) int {
+	units := 0
+	for i, r := range line {
+		if units >= column {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// lspMessage is any message the server writes: a response or a
+// notification.
+type lspMessage struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+	Result json.RawMessage  `json:"result"`
+	Error  *lspError        `json:"error"`
+}
+
+// lspExchange runs the server over messages and returns what it wrote.
+func lspExchange(t *testing.T, messages ...any) []lspMessage {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, msg := range messages {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	s := &lspServer{in: bufio.NewReader(&in), out: &out, docs: make(map[string][]string)}
+	if err := s.run(); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	var written []lspMessage
+	reader := &lspServer{in: bufio.NewReader(&out)}
+	for {
+		body, err := reader.read()
+		if err == io.EOF {
+			return written
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, msg)
+	}
+}
+
+func lspCall(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func lspNotify(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func TestLSPRequests(t *testing.T) {
+	const uri = "file:///tmp/todo.txt"
+	document := "(A) Call Mom +Family @phone due:2025-01-12\n" +
+		"(a) Wrong priority du\n" +
+		"x 2025-01-09 Water plants +Home\n" +
+		"2025-01-08 Write report +W"
+	at := func(line, character int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": character},
+		}
+	}
+	selection := func(start, end int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"range": map[string]any{
+				"start": map[string]any{"line": start, "character": 0},
+				"end":   map[string]any{"line": end, "character": 0},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		params        any
+		expectedError int
+		contains      []string
+		excludes      []string
+	}{
+		{
+			name: "Initialize", method: "initialize", params: map[string]any{},
+			contains: []string{`"completionProvider":{"triggerCharacters":["+","@"]}`, `"name":"todotxt"`},
+		},
+		{
+			name: "Complete projects from the document and todo file", method: "textDocument/completion", params: at(3, 26),
+			contains: []string{`"label":"+Wedding"`, `"label":"+Work"`, `"newText":"+Work"`},
+			excludes: []string{`"label":"+Family"`},
+		},
+		{
+			name: "Complete tag keys", method: "textDocument/completion", params: at(1, 21),
+			contains: []string{`"label":"due:"`},
+			excludes: []string{`"label":"id:"`, `"label":"rec:"`},
+		},
+		{name: "No completion on an empty word", method: "textDocument/completion", params: at(0, 0), contains: []string{`[]`}},
+		{
+			name: "Hover", method: "textDocument/hover", params: at(0, 5),
+			contains: []string{`**Due** 2025-01-12 (in 2 days)`},
+		},
+		{name: "Hover without dates", method: "textDocument/hover", params: at(1, 0), contains: []string{`null`}},
+		{
+			name: "Code actions on one task", method: "textDocument/codeAction", params: selection(0, 0),
+			contains: []string{`"title":"Mark done"`, `"newText":"x 2025-01-10 Call Mom +Family @phone due:2025-01-12"`, `"title":"Set priority B"`, `"title":"Add creation date"`},
+			excludes: []string{`"title":"Set priority A"`},
+		},
+		{
+			name: "Code actions on a selection", method: "textDocument/codeAction", params: selection(2, 4),
+			contains: []string{`"title":"Mark done (1 tasks)"`, `"title":"Set priority A (1 tasks)"`},
+		},
+		{
+			name: "Document symbols", method: "textDocument/documentSymbol", params: at(0, 0),
+			contains: []string{`"name":"+Family"`, `"name":"No Project"`, `"name":"Write report"`, `"detail":"done"`},
+		},
+		{name: "Unknown method", method: "textDocument/rename", params: at(0, 0), expectedError: lspMethodNotFound},
+		{name: "Invalid params", method: "textDocument/hover", params: "line 1", expectedError: lspInvalidParams},
+		{name: "Shutdown", method: "shutdown", params: nil, contains: []string{`null`}},
+	}
+
+	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+	useTestTodoFile(t, "Plan trip +Wedding", "Review +Work")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := lspExchange(t,
+				lspNotify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": document}}),
+				lspCall(7, tt.method, tt.params),
+				lspNotify("exit", nil),
+			)
+			if len(written) != 2 || written[0].Method != "textDocument/publishDiagnostics" {
+				t.Fatalf("Expected diagnostics and one response, got %+v", written)
+			}
+			response := written[1]
+			if response.ID == nil || string(*response.ID) != "7" {
+				t.Fatalf("Response has id %v, want 7", response.ID)
+			}
+
+			if tt.expectedError != 0 {
+				if response.Error == nil || response.Error.Code != tt.expectedError {
+					t.Errorf("error = %+v, want code %d", response.Error, tt.expectedError)
+				}
+				return
+			}
+			if response.Error != nil {
+				t.Fatalf("Unexpected error: %+v", response.Error)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(string(response.Result), s) {
+					t.Errorf("result should contain %s, got %s", s, response.Result)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(string(response.Result), s) {
+					t.Errorf("result should not contain %s, got %s", s, response.Result)
+				}
+			}
+		})
+	}
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	const uri = "file:///tmp/todo.txt"
+	written := lspExchange(t,
+		lspNotify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": "Call Mom\r\nüber due:2025-13-01\r\n"}}),
+		lspNotify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri},
+			"contentChanges": []map[string]any{{"text": "Call Mom\n"}},
+		}),
+		lspNotify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}}),
+		lspNotify("$/cancelRequest", map[string]any{"id": 1}),
+	)
+
+	if len(written) != 3 {
+		t.Fatalf("Expected three diagnostics notifications, got %d", len(written))
+	}
+	var opened struct {
+		URI         string          `json:"uri"`
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(written[0].Params, &opened); err != nil {
+		t.Fatal(err)
+	}
+	if opened.URI != uri || len(opened.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic for %s, got %+v", uri, opened)
+	}
+	// "ü" is two bytes but one UTF-16 unit, so the date at byte 10 starts
+	// at character 9.
+	d := opened.Diagnostics[0]
+	expected := lspRange{Start: lspPosition{Line: 1, Character: 9}, End: lspPosition{Line: 1, Character: 19}}
+	if d.Range != expected || d.Severity != lspSeverityError {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+	for _, msg := range written[1:] {
+		if !strings.Contains(string(msg.Params), `"diagnostics":[]`) {
+			t.Errorf("Expected diagnostics to be cleared, got %s", msg.Params)
+		}
+	}
+}
+
+func TestLSPSymbolsMatchListGroups(t *testing.T) {
+	const uri = "file:///tmp/todo.txt"
+	lines := []string{"Write report +Work", "", "Call Mom +Family +Work", "Water plants", "x 2025-01-09 Book flights +Trip"}
+	written := lspExchange(t,
+		lspNotify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": strings.Join(lines, "\n")}}),
+		lspCall(1, "textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}}),
+	)
+	var symbols []lspDocumentSymbol
+	if err := json.Unmarshal(written[len(written)-1].Result, &symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	todos, _ := todotxt.ParseTodos(lines)
+	groups, _ := todotxt.GroupTodos(todos, "project")
+	if len(symbols) != len(groups) {
+		t.Fatalf("Expected %d groups, got %+v", len(groups), symbols)
+	}
+	for i, g := range groups {
+		if symbols[i].Name != g.Label || len(symbols[i].Children) != len(g.Todos) {
+			t.Errorf("Symbol %d is %s with %d tasks, want %s with %d", i, symbols[i].Name, len(symbols[i].Children), g.Label, len(g.Todos))
+		}
+	}
+	if work := symbols[len(symbols)-2]; work.Range.Start.Line != 0 || work.Range.End.Line != 2 {
+		t.Errorf("+Work should span lines 0-2, got %+v", work.Range)
+	}
+}
@@ -6,11 +6,15 @@ import (
 )
 
 func main() {
+	command, args := parseArgs()
+
+	initSettings()
 	initTodoFile()
 
-	command, args := parseArgs()
+	err := executeCommand(command, args)
+	todoFile.Unlock()
 
-	if err := executeCommand(command, args); err != nil {
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintln(os.Stderr, "Run 'todotxt help' for usage information.")
 		os.Exit(1)
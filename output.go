@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// outputFormat is the global --format option. Commands that support
+// machine-readable output also accept --format after the command name.
+var outputFormat = flag.String("format", "text", "output format: text, json, ndjson, csv or tsv")
+
+var outputFormats = []string{"text", todotxt.FormatJSON, todotxt.FormatNDJSON, todotxt.FormatCSV, todotxt.FormatTSV}
+
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", *outputFormat, "output format: text, json, ndjson, csv or tsv")
+}
+
+func checkFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid format: %s (must be text, json, ndjson, csv or tsv)", format)
+}
+
+// tallyCount is one row of projects or contexts output. An empty Name
+// counts tasks without any project or context.
+type tallyCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// countNames counts how many todos carry each name returned by names,
+// counting todos without any under the empty name.
+func countNames(todos []*todotxt.Todo, names func(*todotxt.Todo) []string) map[string]int {
+	counts := make(map[string]int)
+	for _, todo := range todos {
+		list := names(todo)
+		if len(list) == 0 {
+			counts[""]++
+		}
+		for _, name := range list {
+			counts[name]++
+		}
+	}
+	return counts
+}
+
+// tallyRows sorts counts by name, with the empty name first.
+func tallyRows(counts map[string]int) []tallyCount {
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([]tallyCount, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, tallyCount{Name: name, Count: counts[name]})
+	}
+	return rows
+}
+
+// writeTally writes name counts in a machine-readable format.
+func writeTally(format string, counts map[string]int) error {
+	rows := tallyRows(counts)
+
+	switch format {
+	case todotxt.FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case todotxt.FormatNDJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	table := [][]string{{"name", "count"}}
+	for _, row := range rows {
+		table = append(table, []string{row.Name, strconv.Itoa(row.Count)})
+	}
+	return todotxt.WriteTable(os.Stdout, format, table)
+}
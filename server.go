@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// webFiles is the browser UI served at /, a static page built on the API.
+//
+//go:embed web
+var webFiles embed.FS
+
+// server is the HTTP/JSON API. Requests are handled one at a time, each
+// under the todo file lock and after reloading the file, so edits made by
+// other programs are always seen and never overwritten.
+type server struct {
+	mu sync.Mutex
+}
+
+// httpError is an error with the HTTP status it should be reported as.
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return &httpError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Each request takes the lock for itself.
+	todoFile.Unlock()
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           (&server{}).routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving %s on %s\n", todoFile.Path, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tasks", s.listTasks)
+	mux.HandleFunc("POST /api/tasks", s.createTask)
+	mux.HandleFunc("GET /api/tasks/{id}", s.getTask)
+	mux.HandleFunc("PUT /api/tasks/{id}", s.replaceTask)
+	mux.HandleFunc("PATCH /api/tasks/{id}", s.updateTask)
+	mux.HandleFunc("DELETE /api/tasks/{id}", s.deleteTask)
+	mux.HandleFunc("POST /api/tasks/{id}/complete", s.completeTask)
+	mux.HandleFunc("POST /api/tasks/{id}/uncomplete", s.uncompleteTask)
+	mux.HandleFunc("GET /api/projects", s.listProjects)
+	mux.HandleFunc("GET /api/contexts", s.listContexts)
+	mux.HandleFunc("POST /api/archive", s.archive)
+
+	web, _ := fs.Sub(webFiles, "web")
+	mux.Handle("GET /", http.FileServerFS(web))
+	return protect(mux)
+}
+
+// protect refuses requests that change the todo file unless they come from
+// the server's own origin or from a client that is not a browser, and,
+// apart from DELETE, carry a JSON body. Browsers cannot send a JSON body
+// to another site without a CORS preflight, which the server never allows,
+// so a web page elsewhere cannot add, complete or archive tasks.
+func protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !sameOrigin(r) {
+			writeResult(w, 0, nil, &httpError{http.StatusForbidden, "cross-origin requests are not allowed"})
+			return
+		}
+		if r.Method != http.MethodDelete {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeResult(w, 0, nil, &httpError{http.StatusUnsupportedMediaType, "Content-Type must be application/json"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin reports whether a request was not sent by a page on another
+// site, going by Sec-Fetch-Site or else Origin. Clients such as curl send
+// neither.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// read serves a request that does not change the todo file.
+func (s *server) read(w http.ResponseWriter, fn func() (any, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := func() (any, error) {
+		if err := todoFile.Lock(); err != nil {
+			return nil, err
+		}
+		defer todoFile.Unlock()
+
+		if err := todoFile.Load(); err != nil {
+			return nil, err
+		}
+		return fn()
+	}()
+	writeResult(w, http.StatusOK, result, err)
+}
+
+// change serves a request that modifies the todo file: fn runs on freshly
+// loaded tasks, the result is saved and the change is recorded in the
+// journal as the equivalent command.
+func (s *server) change(w http.ResponseWriter, status int, name string, args []string, fn func() (any, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result any
+	err := func() error {
+		if err := todoFile.Lock(); err != nil {
+			return err
+		}
+		defer todoFile.Unlock()
+
+		if err := todoFile.Load(); err != nil {
+			return err
+		}
+
+		err := runJournaled(name, args, func([]string) error {
+			var err error
+			if result, err = fn(); err != nil {
+				return err
+			}
+			return saveFile()
+		})
+		if err != nil {
+			todoFile.Load()
+		}
+		return err
+	}()
+	writeResult(w, status, result, err)
+}
+
+func writeResult(w http.ResponseWriter, status int, result any, err error) {
+	if err != nil {
+		var httpErr *httpError
+		var queryErr *todotxt.QueryError
+		switch {
+		case errors.As(err, &httpErr):
+			status = httpErr.status
+		case errors.As(err, &queryErr):
+			status = http.StatusBadRequest
+		case errors.Is(err, todotxt.ErrConflict):
+			status = http.StatusConflict
+		default:
+			status = http.StatusInternalServerError
+		}
+		result = map[string]string{"error": err.Error()}
+	}
+	writeJSON(w, status, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// lookupTask finds the task named by the {id} path value, a line number or
+// stable id: value. With an expect parameter the task's line must still be
+// that text, so a line number that now names another task, or a task
+// edited elsewhere, is a conflict rather than a change to the wrong task.
+func lookupTask(r *http.Request) (*todotxt.Todo, error) {
+	ref := r.PathValue("id")
+	todo := todoFile.Lookup(ref)
+	if todo == nil {
+		return nil, &httpError{http.StatusNotFound, fmt.Sprintf("task %s not found", ref)}
+	}
+	if expect, ok := r.URL.Query()["expect"]; ok && todo.String() != expect[0] {
+		return nil, &httpError{http.StatusConflict, fmt.Sprintf("task %s has changed since it was read", ref)}
+	}
+	return todo, nil
+}
+
+// readTask decodes a request body in the import schema into a task,
+// checked and with relative dates resolved as import does.
+func readTask(r *http.Request) (*todotxt.Todo, error) {
+	todos, rowErrors, err := todotxt.ReadRecords(io.LimitReader(r.Body, 1<<20), todotxt.FormatNDJSON)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if len(rowErrors) > 0 {
+		return nil, badRequest("%v", rowErrors[0].Err)
+	}
+	if len(todos) != 1 {
+		return nil, badRequest("expected one task object, got %d", len(todos))
+	}
+	return todos[0], nil
+}
+
+func records(todos []*todotxt.Todo) []todotxt.Record {
+	out := make([]todotxt.Record, 0, len(todos))
+	for _, todo := range todos {
+		out = append(out, todo.Record())
+	}
+	return out
+}
+
+// listTasks returns the tasks selected by the q parameter, with the same
+// meaning as the list filter, sorted by the optional sort parameter.
+func (s *server) listTasks(w http.ResponseWriter, r *http.Request) {
+	s.read(w, func() (any, error) {
+		var args []string
+		if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+			args = []string{q}
+		}
+		deferred, _ := strconv.ParseBool(r.URL.Query().Get("all_thresholds"))
+		todos, err := filterTodos(args, deferred)
+		if err != nil {
+			return nil, err
+		}
+		spec := r.URL.Query().Get("sort")
+		if spec == "" {
+			spec = settings.Sort
+		}
+		if spec != "" {
+			keys, err := todotxt.ParseSortKeys(spec)
+			if err != nil {
+				return nil, badRequest("%v", err)
+			}
+			todotxt.SortTodosBy(todos, keys)
+		}
+		return records(todos), nil
+	})
+}
+
+func (s *server) getTask(w http.ResponseWriter, r *http.Request) {
+	s.read(w, func() (any, error) {
+		todo, err := lookupTask(r)
+		if err != nil {
+			return nil, err
+		}
+		return todo.Record(), nil
+	})
+}
+
+func (s *server) createTask(w http.ResponseWriter, r *http.Request) {
+	todo, err := readTask(r)
+	if err != nil {
+		writeResult(w, 0, nil, err)
+		return
+	}
+
+	s.change(w, http.StatusCreated, "add", []string{todo.String()}, func() (any, error) {
+		if err := addTask(todo); err != nil {
+			return nil, err
+		}
+		return todo.Record(), nil
+	})
+}
+
+// replaceTask overwrites a task with the line described by the body.
+func (s *server) replaceTask(w http.ResponseWriter, r *http.Request) {
+	replacement, err := readTask(r)
+	if err != nil {
+		writeResult(w, 0, nil, err)
+		return
+	}
+
+	s.change(w, http.StatusOK, "edit", []string{r.PathValue("id")}, func() (any, error) {
+		todo, err := lookupTask(r)
+		if err != nil {
+			return nil, err
+		}
+		todoFile.Replace(todo, replacement)
+		return replacement.Record(), nil
+	})
+}
+
+// taskPatch is the body of PATCH /api/tasks/{id}. Unset fields are left
+// alone; an empty priority removes it.
+type taskPatch struct {
+	Priority *string `json:"priority"`
+	Complete *bool   `json:"complete"`
+}
+
+func (s *server) updateTask(w http.ResponseWriter, r *http.Request) {
+	var patch taskPatch
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		writeResult(w, 0, nil, badRequest("invalid patch: %v", err))
+		return
+	}
+
+	var priority todotxt.Priority = todotxt.PriorityNone
+	if patch.Priority != nil && *patch.Priority != "" {
+		p := strings.ToUpper(*patch.Priority)
+		if len(p) != 1 || p[0] < 'A' || p[0] > 'Z' {
+			writeResult(w, 0, nil, badRequest("invalid priority: %s (must be A-Z)", *patch.Priority))
+			return
+		}
+		priority = todotxt.Priority(p[0])
+	}
+
+	id := r.PathValue("id")
+	name, args := "edit", []string{id}
+	switch {
+	case patch.Complete != nil && patch.Priority == nil:
+		name = "undo"
+		if *patch.Complete {
+			name = "do"
+		}
+	case patch.Priority != nil && patch.Complete == nil:
+		name = "depri"
+		if priority != todotxt.PriorityNone {
+			name, args = "pri", []string{id, string(rune(priority))}
+		}
+	}
+
+	s.change(w, http.StatusOK, name, args, func() (any, error) {
+		todo, err := lookupTask(r)
+		if err != nil {
+			return nil, err
+		}
+		if patch.Complete != nil && *patch.Complete != todo.Complete {
+			if *patch.Complete {
+				if _, err := completeTodo(todo); err != nil {
+					return nil, badRequest("%v", err)
+				}
+			} else {
+				todo.MarkUncomplete()
+			}
+		}
+		if patch.Priority != nil {
+			if todo.Complete && priority != todotxt.PriorityNone {
+				return nil, badRequest("completed tasks cannot have priorities")
+			}
+			todo.SetPriority(priority)
+		}
+		return todo.Record(), nil
+	})
+}
+
+func (s *server) deleteTask(w http.ResponseWriter, r *http.Request) {
+	s.change(w, http.StatusOK, "delete", []string{r.PathValue("id")}, func() (any, error) {
+		todo, err := lookupTask(r)
+		if err != nil {
+			return nil, err
+		}
+		record := todo.Record()
+		if !todoFile.Delete(todo.ID) {
+			return nil, fmt.Errorf("failed to delete task %s", r.PathValue("id"))
+		}
+		return record, nil
+	})
+}
+
+func (s *server) completeTask(w http.ResponseWriter, r *http.Request) {
+	s.change(w, http.StatusOK, "do", []string{r.PathValue("id")}, func() (any, error) {
+		todo, err := lookupTask(r)
+		if err != nil {
+			return nil, err
+		}
+		if !todo.Complete {
+			if _, err := completeTodo(todo); err != nil {
+				return nil, badRequest("%v", err)
+			}
+		}
+		return todo.Record(), nil
+	})
+}
+
+func (s *server) uncompleteTask(w http.ResponseWriter, r *http.Request) {
+	s.change(w, http.StatusOK, "undo", []string{r.PathValue("id")}, func() (any, error) {
+		todo, err := lookupTask(r)
+		if err != nil {
+			return nil, err
+		}
+		todo.MarkUncomplete()
+		return todo.Record(), nil
+	})
+}
+
+func (s *server) listProjects(w http.ResponseWriter, r *http.Request) {
+	s.read(w, func() (any, error) {
+		return tallyTodos(r, func(todo *todotxt.Todo) []string { return todo.Projects })
+	})
+}
+
+func (s *server) listContexts(w http.ResponseWriter, r *http.Request) {
+	s.read(w, func() (any, error) {
+		return tallyTodos(r, func(todo *todotxt.Todo) []string { return todo.Contexts })
+	})
+}
+
+// tallyTodos counts names over active tasks, or all tasks with ?all=true,
+// in the same shape as projects --format json.
+func tallyTodos(r *http.Request, names func(*todotxt.Todo) []string) ([]tallyCount, error) {
+	todos := todoFile.GetActive(todotxt.Now())
+	if all, _ := strconv.ParseBool(r.URL.Query().Get("all")); all {
+		todos = todoFile.Todos
+	}
+	return tallyRows(countNames(todos, names)), nil
+}
+
+func (s *server) archive(w http.ResponseWriter, r *http.Request) {
+	s.change(w, http.StatusOK, "archive", nil, func() (any, error) {
+		archived, err := archiveCompleted()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"archived": archived}, nil
+	})
+}
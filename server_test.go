@@ -0,0 +1,291 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// newTestServer serves a todo file holding lines, with done.txt next to it.
+func newTestServer(t *testing.T, lines ...string) http.Handler {
+	t.Helper()
+	useTestTodoFile(t, lines...)
+	return (&server{}).routes()
+}
+
+// serveJSON sends a request with a JSON body, as a non-browser client.
+func serveJSON(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProtect(t *testing.T) {
+	handler := protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		expected int
+	}{
+		{name: "Read", method: "GET", expected: http.StatusNoContent},
+		{name: "Cross-site read", method: "GET", headers: map[string]string{"Origin": "http://evil.example"}, expected: http.StatusNoContent},
+		{name: "JSON from curl", method: "POST", headers: map[string]string{"Content-Type": "application/json"}, expected: http.StatusNoContent},
+		{name: "JSON with charset", method: "PUT", headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}, expected: http.StatusNoContent},
+		{name: "No body", method: "POST", expected: http.StatusUnsupportedMediaType},
+		{name: "Plain text", method: "POST", headers: map[string]string{"Content-Type": "text/plain"}, expected: http.StatusUnsupportedMediaType},
+		{name: "Form", method: "PATCH", headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, expected: http.StatusUnsupportedMediaType},
+		{name: "Delete without body", method: "DELETE", expected: http.StatusNoContent},
+		{
+			name:     "Same origin",
+			method:   "POST",
+			headers:  map[string]string{"Content-Type": "application/json", "Origin": "http://todo.test", "Sec-Fetch-Site": "same-origin"},
+			expected: http.StatusNoContent,
+		},
+		{
+			name:     "Cross-site fetch",
+			method:   "POST",
+			headers:  map[string]string{"Content-Type": "application/json", "Sec-Fetch-Site": "cross-site"},
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "Same site, other origin",
+			method:   "POST",
+			headers:  map[string]string{"Content-Type": "application/json", "Sec-Fetch-Site": "same-site"},
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "Other origin without Sec-Fetch-Site",
+			method:   "DELETE",
+			headers:  map[string]string{"Origin": "http://evil.example"},
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "Opaque origin",
+			method:   "POST",
+			headers:  map[string]string{"Content-Type": "application/json", "Origin": "null"},
+			expected: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://todo.test/api/archive", strings.NewReader("{}"))
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expected {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expected, rec.Body)
+			}
+		})
+	}
+}
+
+func TestServerExpect(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		expected int
+		file     string
+	}{
+		{
+			name:     "Without expect",
+			target:   "/api/tasks/2/complete",
+			expected: http.StatusOK,
+			file:     "Call Mom\nx 2025-01-10 Pay rent\n",
+		},
+		{
+			name:     "Matching line",
+			target:   "/api/tasks/2/complete?expect=" + url.QueryEscape("Pay rent"),
+			expected: http.StatusOK,
+			file:     "Call Mom\nx 2025-01-10 Pay rent\n",
+		},
+		{
+			name:     "Line now holds another task",
+			target:   "/api/tasks/2/complete?expect=" + url.QueryEscape("Water plants"),
+			expected: http.StatusConflict,
+			file:     "Call Mom\nPay rent\n",
+		},
+		{
+			name:     "Empty expect",
+			target:   "/api/tasks/2/complete?expect=",
+			expected: http.StatusConflict,
+			file:     "Call Mom\nPay rent\n",
+		},
+		{
+			name:     "Missing task",
+			target:   "/api/tasks/5/complete?expect=" + url.QueryEscape("Pay rent"),
+			expected: http.StatusNotFound,
+			file:     "Call Mom\nPay rent\n",
+		},
+	}
+
+	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestServer(t, "Call Mom", "Pay rent")
+			rec := serveJSON(handler, "POST", tt.target, "{}")
+			if rec.Code != tt.expected {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expected, rec.Body)
+			}
+			if got := readTodoFile(t); got != tt.file {
+				t.Errorf("todo.txt = %q, want %q", got, tt.file)
+			}
+		})
+	}
+}
+
+func TestServerHandlers(t *testing.T) {
+	lines := []string{
+		"(B) Call Mom +Family @phone",
+		"Pay rent due:2025-01-09 id:rent",
+		"x 2025-01-05 Water plants +Home",
+	}
+	unchanged := strings.Join(lines, "\n") + "\n"
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		expected int
+		contains []string
+		excludes []string
+		file     string
+	}{
+		{
+			name: "List open tasks", method: "GET", target: "/api/tasks", expected: http.StatusOK,
+			contains: []string{`"text": "(B) Call Mom +Family @phone"`, `"text": "Pay rent due:2025-01-09 id:rent"`},
+			excludes: []string{"Water plants"},
+		},
+		{
+			name: "List with a query", method: "GET", target: "/api/tasks?q=" + url.QueryEscape("+Family or id:rent"), expected: http.StatusOK,
+			contains: []string{"Call Mom", "Pay rent"}, excludes: []string{"Water plants"},
+		},
+		{
+			name: "List done with a query", method: "GET", target: "/api/tasks?q=" + url.QueryEscape("done +Home"), expected: http.StatusOK,
+			contains: []string{"Water plants"}, excludes: []string{"Call Mom", "Pay rent"},
+		},
+		{
+			name: "List all with a query", method: "GET", target: "/api/tasks?q=" + url.QueryEscape("all +Family or +Home"), expected: http.StatusOK,
+			contains: []string{"Call Mom", "Water plants"}, excludes: []string{"Pay rent"},
+		},
+		{name: "Invalid query", method: "GET", target: "/api/tasks?q=" + url.QueryEscape("(+Family"), expected: http.StatusBadRequest},
+		{name: "Invalid sort", method: "GET", target: "/api/tasks?sort=priority,-", expected: http.StatusBadRequest},
+		{name: "Get by id tag", method: "GET", target: "/api/tasks/rent", expected: http.StatusOK, contains: []string{`"id": 2`}},
+		{name: "Get missing task", method: "GET", target: "/api/tasks/9", expected: http.StatusNotFound},
+		{
+			name: "List projects", method: "GET", target: "/api/projects?all=true", expected: http.StatusOK,
+			contains: []string{`"name": "Family"`, `"name": "Home"`},
+		},
+		{
+			name: "Create", method: "POST", target: "/api/tasks", body: `{"text": "Buy milk due:tomorrow"}`, expected: http.StatusCreated,
+			contains: []string{`"id": 4`}, file: unchanged + "Buy milk due:2025-01-11\n",
+		},
+		{
+			name: "Create from fields", method: "POST", target: "/api/tasks", body: `{"description": "Buy milk", "priority": "A", "contexts": ["store"]}`,
+			expected: http.StatusCreated, file: unchanged + "(A) Buy milk @store\n",
+		},
+		{name: "Create an invalid task", method: "POST", target: "/api/tasks", body: `{"text": "(a) Buy milk"}`, expected: http.StatusBadRequest},
+		{name: "Create from malformed JSON", method: "POST", target: "/api/tasks", body: `{"text": `, expected: http.StatusBadRequest},
+		{
+			name: "Replace", method: "PUT", target: "/api/tasks/1", body: `{"text": "(A) Call Dad +Family"}`, expected: http.StatusOK,
+			file: "(A) Call Dad +Family\n" + lines[1] + "\n" + lines[2] + "\n",
+		},
+		{
+			name: "Set priority", method: "PATCH", target: "/api/tasks/rent", body: `{"priority": "a"}`, expected: http.StatusOK,
+			file: lines[0] + "\n(A) Pay rent due:2025-01-09 id:rent\n" + lines[2] + "\n",
+		},
+		{
+			name: "Remove priority", method: "PATCH", target: "/api/tasks/1", body: `{"priority": ""}`, expected: http.StatusOK,
+			file: "Call Mom +Family @phone\n" + lines[1] + "\n" + lines[2] + "\n",
+		},
+		{name: "Invalid priority", method: "PATCH", target: "/api/tasks/1", body: `{"priority": "1"}`, expected: http.StatusBadRequest},
+		{name: "Unknown patch field", method: "PATCH", target: "/api/tasks/1", body: `{"due": "2025-01-20"}`, expected: http.StatusBadRequest},
+		{name: "Priority on a completed task", method: "PATCH", target: "/api/tasks/3", body: `{"priority": "A"}`, expected: http.StatusBadRequest},
+		{
+			name: "Complete by patch", method: "PATCH", target: "/api/tasks/2", body: `{"complete": true}`, expected: http.StatusOK,
+			file: lines[0] + "\nx 2025-01-10 Pay rent due:2025-01-09 id:rent\n" + lines[2] + "\n",
+		},
+		{
+			name: "Complete", method: "POST", target: "/api/tasks/1/complete", body: `{}`, expected: http.StatusOK,
+			contains: []string{`"complete": true`}, file: "x 2025-01-10 Call Mom +Family @phone\n" + lines[1] + "\n" + lines[2] + "\n",
+		},
+		{
+			name: "Uncomplete", method: "POST", target: "/api/tasks/3/uncomplete", body: `{}`, expected: http.StatusOK,
+			file: lines[0] + "\n" + lines[1] + "\nWater plants +Home\n",
+		},
+		{
+			name: "Delete", method: "DELETE", target: "/api/tasks/rent", expected: http.StatusOK,
+			contains: []string{"Pay rent"}, file: lines[0] + "\n" + lines[2] + "\n",
+		},
+		{name: "Delete missing task", method: "DELETE", target: "/api/tasks/9", expected: http.StatusNotFound},
+		{
+			name: "Archive", method: "POST", target: "/api/archive", body: `{}`, expected: http.StatusOK,
+			contains: []string{`"archived": 1`}, file: lines[0] + "\n" + lines[1] + "\n",
+		},
+	}
+
+	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestServer(t, lines...)
+			rec := serveJSON(handler, tt.method, tt.target, tt.body)
+			if rec.Code != tt.expected {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expected, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(rec.Body.String(), s) {
+					t.Errorf("response should contain %s, got %s", s, rec.Body)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(rec.Body.String(), s) {
+					t.Errorf("response should not contain %s, got %s", s, rec.Body)
+				}
+			}
+			file := tt.file
+			if file == "" {
+				file = unchanged
+			}
+			if got := readTodoFile(t); got != file {
+				t.Errorf("todo.txt = %q, want %q", got, file)
+			}
+		})
+	}
+}
+
+func TestServerChangesAreJournaled(t *testing.T) {
+	handler := newTestServer(t, "Call Mom")
+
+	rec := serveJSON(handler, "POST", "/api/tasks", `{"text": "Pay rent"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+
+	op, err := todotxt.NewJournal(journalPath()).Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if op.Command != "add Pay rent" {
+		t.Errorf("Journaled command = %q", op.Command)
+	}
+	if got := readTodoFile(t); got != "Call Mom\n" {
+		t.Errorf("todo.txt after undo = %q", got)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+// Config is a parsed config file. The top-level settings apply to every
+// profile; a [profiles.NAME] table overrides them for that profile.
+//
+//	profile = "home"             # used when no profile is selected
+//	sort = "priority,due"
+//
+//	[colors]
+//	priority_a = "bold red"
+//
+//	[profiles.work]
+//	todo_file = "~/work/todo.txt"
+//	done_file = "~/work/done.txt"
+//
+// The file format is a subset of TOML: tables, and keys set to strings,
+// booleans or integers.
+type Config struct {
+	Settings
+	Profile  string
+	Profiles map[string]*Settings
+}
+
+// Settings are the options a config file can set, at the top level or for
+// a profile. Empty strings and nil pointers are unset.
+type Settings struct {
+	TodoFile         string
+	DoneFile         string
+	Sort             string
+	Filter           string
+	Color            string
+	Colors           map[string]string
+	AutoCreationDate *bool
+	StableIDs        *bool
+	Timezone         string
+}
+
+// colorKeys lists the keys of the [colors] table.
+var colorKeys = []string{"done", "overdue", "priority_a", "priority_b", "priority_c", "priority"}
+
+// colorModes lists the values of the color setting.
+var colorModes = []string{"auto", "always", "never"}
+
+// loadConfig reads the config file at path. A missing file is an empty
+// config.
+func loadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{Profiles: make(map[string]*Settings)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config, err := parseConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return config, nil
+}
+
+// parseConfig parses a config file. Errors name the offending line as
+// "N: message".
+func parseConfig(r io.Reader) (*Config, error) {
+	config := &Config{Profiles: make(map[string]*Settings)}
+	err := parseTOML(r, func(table []string, key string, value any) error {
+		switch {
+		case key == "":
+			if len(table) == 2 && table[0] == "profiles" && config.Profiles[table[1]] == nil {
+				config.Profiles[table[1]] = &Settings{}
+			}
+			if len(table) == 1 && table[0] == "colors" ||
+				len(table) == 2 && table[0] == "profiles" ||
+				len(table) == 3 && table[0] == "profiles" && table[2] == "colors" {
+				return nil
+			}
+		case len(table) == 0 && key == "profile":
+			name, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string", key)
+			}
+			config.Profile = name
+			return nil
+		case len(table) == 0:
+			return config.Settings.set(key, value)
+		case len(table) == 1 && table[0] == "colors":
+			return config.Settings.setColor(key, value)
+		case len(table) == 2:
+			return config.Profiles[table[1]].set(key, value)
+		case len(table) == 3:
+			profile := config.Profiles[table[1]]
+			if profile == nil {
+				profile = &Settings{}
+				config.Profiles[table[1]] = profile
+			}
+			return profile.setColor(key, value)
+		}
+		return fmt.Errorf("unknown table [%s]", strings.Join(table, "."))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (s *Settings) set(key string, value any) error {
+	switch key {
+	case "todo_file", "done_file", "sort", "filter", "color", "timezone":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", key)
+		}
+		switch key {
+		case "todo_file":
+			s.TodoFile = str
+		case "done_file":
+			s.DoneFile = str
+		case "sort":
+			if _, err := todotxt.ParseSortKeys(str); err != nil {
+				return err
+			}
+			s.Sort = str
+		case "filter":
+			s.Filter = str
+		case "color":
+			if !slices.Contains(colorModes, str) {
+				return fmt.Errorf("invalid color %q (want %s)", str, strings.Join(colorModes, ", "))
+			}
+			s.Color = str
+		case "timezone":
+			if _, err := time.LoadLocation(str); err != nil {
+				return fmt.Errorf("invalid timezone %q", str)
+			}
+			s.Timezone = str
+		}
+	case "auto_creation_date", "stable_ids":
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		if key == "auto_creation_date" {
+			s.AutoCreationDate = &b
+		} else {
+			s.StableIDs = &b
+		}
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+func (s *Settings) setColor(key string, value any) error {
+	if !slices.Contains(colorKeys, key) {
+		return fmt.Errorf("unknown color %q (want %s)", key, strings.Join(colorKeys, ", "))
+	}
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("color %s must be a string", key)
+	}
+	if s.Colors == nil {
+		s.Colors = make(map[string]string)
+	}
+	s.Colors[key] = str
+	return nil
+}
+
+// Resolve returns the top-level settings overridden by those of the named
+// profile, or of the config's default profile when name is empty.
+func (c *Config) Resolve(name string) (Settings, error) {
+	resolved := c.Settings
+	resolved.Colors = make(map[string]string)
+	for key, value := range c.Colors {
+		resolved.Colors[key] = value
+	}
+
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return resolved, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return Settings{}, fmt.Errorf("unknown profile %q (no profiles are configured)", name)
+		}
+		return Settings{}, fmt.Errorf("unknown profile %q (have %s)", name, strings.Join(names, ", "))
+	}
+
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	override(&resolved.TodoFile, profile.TodoFile)
+	override(&resolved.DoneFile, profile.DoneFile)
+	override(&resolved.Sort, profile.Sort)
+	override(&resolved.Filter, profile.Filter)
+	override(&resolved.Color, profile.Color)
+	override(&resolved.Timezone, profile.Timezone)
+	if profile.AutoCreationDate != nil {
+		resolved.AutoCreationDate = profile.AutoCreationDate
+	}
+	if profile.StableIDs != nil {
+		resolved.StableIDs = profile.StableIDs
+	}
+	for key, value := range profile.Colors {
+		resolved.Colors[key] = value
+	}
+	return resolved, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestParseConfig(t *testing.T) {
+	input := `# todotxt config
+profile = "home"
+sort = "priority,due"   # trailing comment
+filter = 'not pri:Z and "a # b"'
+color = "auto"
+auto_creation_date = true
+
+[colors]
+priority_a = "bold red"
+done = "gray"
+
+[profiles.home]
+todo_file = "~/todo.txt"
+
+[profiles."side project"]
+todo_file = "/tmp/side\ttodo.txt"
+stable_ids = false
+
+[profiles.side-project.colors]
+overdue = "é"
+`
+
+	config, err := parseConfig(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseConfig failed: %v", err)
+	}
+
+	expected := &Config{
+		Settings: Settings{
+			Sort:             "priority,due",
+			Filter:           `not pri:Z and "a # b"`,
+			Color:            "auto",
+			AutoCreationDate: boolPtr(true),
+			Colors:           map[string]string{"priority_a": "bold red", "done": "gray"},
+		},
+		Profile: "home",
+		Profiles: map[string]*Settings{
+			"home":         {TodoFile: "~/todo.txt"},
+			"side project": {TodoFile: "/tmp/side\ttodo.txt", StableIDs: boolPtr(false)},
+			"side-project": {Colors: map[string]string{"overdue": "é"}},
+		},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("parseConfig() = %+v, want %+v", config, expected)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Unknown key", input: "\nsort = \"due\"\nfoo = 1", expected: `3: unknown key "foo"`},
+		{name: "Unknown table", input: "[views]\nsort = \"due\"", expected: "1: unknown table [views]"},
+		{name: "Wrong type", input: "auto_creation_date = \"yes\"", expected: "1: auto_creation_date must be true or false"},
+		{name: "Invalid sort", input: "sort = \"priority,,due\"", expected: `1: empty sort key`},
+		{name: "Invalid color mode", input: "color = \"sometimes\"", expected: `1: invalid color "sometimes" (want auto, always, never)`},
+		{name: "Unknown color", input: "[colors]\nurgent = \"red\"", expected: `2: unknown color "urgent"`},
+		{name: "Invalid timezone", input: "timezone = \"Mars/Base\"", expected: `1: invalid timezone "Mars/Base"`},
+		{name: "Duplicate key", input: "sort = \"due\"\nsort = \"priority\"", expected: `2: duplicate key "sort"`},
+		{name: "Duplicate table", input: "[colors]\n[colors]", expected: "2: duplicate table [colors]"},
+		{name: "Dotted key", input: "colors.done = \"gray\"", expected: "1: dotted keys are not supported"},
+		{name: "Unterminated string", input: "sort = \"due", expected: "1: unterminated string"},
+		{name: "Invalid escape", input: `filter = "\q"`, expected: `1: invalid escape \q`},
+		{name: "Unsupported value", input: "filter = [1, 2]", expected: "1: unsupported value"},
+		{name: "Trailing text", input: "sort = \"due\" x", expected: `1: unexpected "x"`},
+		{name: "Missing equals", input: "sort \"due\"", expected: `1: expected '=' after key "sort"`},
+		{name: "Missing value", input: "sort =", expected: "1: missing value"},
+		{name: "Unclosed table", input: "[colors", expected: `1: expected ']' after key "colors"`},
+		{name: "Empty header", input: "[]", expected: "1: invalid key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("error = %q, want prefix %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfigResolve(t *testing.T) {
+	config := &Config{
+		Settings: Settings{
+			TodoFile:         "~/todo.txt",
+			Sort:             "priority",
+			AutoCreationDate: boolPtr(true),
+			Colors:           map[string]string{"done": "gray", "overdue": "red"},
+		},
+		Profile: "home",
+		Profiles: map[string]*Settings{
+			"home": {},
+			"work": {
+				TodoFile:         "~/work/todo.txt",
+				DoneFile:         "~/work/done.txt",
+				AutoCreationDate: boolPtr(false),
+				Colors:           map[string]string{"overdue": "magenta"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		profile  string
+		expected Settings
+		err      string
+	}{
+		{
+			name:    "Default profile",
+			profile: "",
+			expected: Settings{
+				TodoFile:         "~/todo.txt",
+				Sort:             "priority",
+				AutoCreationDate: boolPtr(true),
+				Colors:           map[string]string{"done": "gray", "overdue": "red"},
+			},
+		},
+		{
+			name:    "Named profile overrides top level",
+			profile: "work",
+			expected: Settings{
+				TodoFile:         "~/work/todo.txt",
+				DoneFile:         "~/work/done.txt",
+				Sort:             "priority",
+				AutoCreationDate: boolPtr(false),
+				Colors:           map[string]string{"done": "gray", "overdue": "magenta"},
+			},
+		},
+		{
+			name:    "Unknown profile",
+			profile: "school",
+			err:     `unknown profile "school" (have home, work)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := config.Resolve(tt.profile)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Resolve(%q) error = %v, want %q", tt.profile, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) failed: %v", tt.profile, err)
+			}
+			if !reflect.DeepEqual(settings, tt.expected) {
+				t.Errorf("Resolve(%q) = %+v, want %+v", tt.profile, settings, tt.expected)
+			}
+		})
+	}
+
+	if config.Colors["overdue"] != "red" {
+		t.Error("Resolve modified the config's colors")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := loadConfig(filepath.Join(dir, "missing.toml"))
+	if err != nil {
+		t.Fatalf("loadConfig of a missing file failed: %v", err)
+	}
+	if settings, err := config.Resolve(""); err != nil || settings.TodoFile != "" {
+		t.Errorf("missing config resolved to %+v, %v", settings, err)
+	}
+
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("sort = \"due\"\nbogus = true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadConfig(path)
+	if err == nil || err.Error() != path+`:2: unknown key "bogus"` {
+		t.Errorf("loadConfig error = %v", err)
+	}
+}
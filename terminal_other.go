@@ -0,0 +1,23 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// The tui drives the terminal with stty(1), which is only available on
+// Unix systems.
+
+func rawTerminal() (func(), error) {
+	return nil, errors.New("the tui command is not supported on this platform")
+}
+
+func terminalSize() (int, int) {
+	return 24, 80
+}
+
+func watchTerminalSize() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
@@ -0,0 +1,60 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// rawTerminal switches the terminal on standard input to raw mode without
+// echo and returns a function that restores its previous settings.
+func rawTerminal() (func(), error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("standard input is not a terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %w", err)
+	}
+	return func() {
+		stty(strings.TrimSpace(state))
+	}, nil
+}
+
+// terminalSize returns the rows and columns of the terminal, or 24x80 if
+// they cannot be determined.
+func terminalSize() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		fields := strings.Fields(out)
+		if len(fields) == 2 {
+			rows, err1 := strconv.Atoi(fields[0])
+			cols, err2 := strconv.Atoi(fields[1])
+			if err1 == nil && err2 == nil && rows > 0 && cols > 0 {
+				return rows, cols
+			}
+		}
+	}
+	return 24, 80
+}
+
+// watchTerminalSize returns a channel that receives a value whenever the
+// terminal is resized, and a function that stops the notifications.
+func watchTerminalSize() (<-chan os.Signal, func()) {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	return resized, func() { signal.Stop(resized) }
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,64 @@
+package todotxt
+
+import "time"
+
+// AgendaSection is one section of an agenda. Date is the day of a day
+// section and nil for the Overdue and Undated sections.
+type AgendaSection struct {
+	Title string
+	Date  *time.Time
+	Todos []*Todo
+}
+
+// Agenda sorts the open tasks of todos into sections: Overdue, Today,
+// Tomorrow and one per following day, for a window of days days starting
+// today, and then undated tasks with a priority of minPriority or higher.
+// Days are those of the package clock. Every section is returned, even an
+// empty one; tasks are ordered by due date and priority.
+func Agenda(todos []*Todo, days int, minPriority Priority) []AgendaSection {
+	var open []*Todo
+	for _, todo := range todos {
+		if !todo.Complete {
+			open = append(open, todo)
+		}
+	}
+	SortTodosBy(open, []SortKey{{Field: "due"}, {Field: "priority"}})
+
+	sections := []AgendaSection{{Title: "Overdue", Todos: FilterOverdue(open)}}
+
+	today := Today()
+	for i := 0; i < days; i++ {
+		date := today.AddDate(0, 0, i)
+		section := AgendaSection{Title: date.Weekday().String(), Date: &date}
+		switch i {
+		case 0:
+			section.Title = "Today"
+			section.Todos = FilterToday(open)
+		case 1:
+			section.Title = "Tomorrow"
+			section.Todos = FilterTodos(open, dueOn(date))
+		default:
+			section.Todos = FilterTodos(open, dueOn(date))
+		}
+		sections = append(sections, section)
+	}
+
+	undated := FilterTodos(open, func(t *Todo) bool {
+		return t.GetDueDate() == nil && t.Priority != PriorityNone && t.Priority <= minPriority
+	})
+	sections = append(sections, AgendaSection{Title: "Undated high priority", Todos: undated})
+
+	for i := range sections {
+		if sections[i].Todos == nil {
+			sections[i].Todos = []*Todo{}
+		}
+	}
+	return sections
+}
+
+func dueOn(date time.Time) Predicate {
+	return func(t *Todo) bool {
+		due := t.GetDueDate()
+		return due != nil && due.Equal(date)
+	}
+}
@@ -0,0 +1,55 @@
+package todotxt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAgenda(t *testing.T) {
+	// Friday morning in Tokyo, still Thursday in UTC.
+	defer SetClock(SetClock(FixedClock(time.Date(2025, 1, 10, 8, 0, 0, 0, time.FixedZone("JST", 9*60*60)))))
+
+	todos := parseTestTodos(t,
+		"(C) Renew passport due:2025-01-07",
+		"(A) Pay rent due:2025-01-09",
+		"Stand-up notes due:2025-01-10",
+		"(B) Review budget due:2025-01-10",
+		"Buy milk due:2025-01-11",
+		"Plan sprint due:2025-01-13",
+		"Ship release due:2025-01-14",
+		"(A) Call Mom",
+		"(B) Book dentist",
+		"(C) Clean garage",
+		"x 2025-01-08 (A) File taxes due:2025-01-08",
+		"Read a book",
+	)
+
+	type section struct {
+		Title string
+		Date  string
+		IDs   []int
+	}
+	expected := []section{
+		{Title: "Overdue", IDs: []int{1, 2}},
+		{Title: "Today", Date: "2025-01-10", IDs: []int{4, 3}},
+		{Title: "Tomorrow", Date: "2025-01-11", IDs: []int{5}},
+		{Title: "Sunday", Date: "2025-01-12", IDs: []int{}},
+		{Title: "Monday", Date: "2025-01-13", IDs: []int{6}},
+		{Title: "Undated high priority", IDs: []int{8, 9}},
+	}
+
+	var got []section
+	for _, s := range Agenda(todos, 4, PriorityB) {
+		got = append(got, section{Title: s.Title, IDs: []int{}})
+		if s.Date != nil {
+			got[len(got)-1].Date = s.Date.Format("2006-01-02")
+		}
+		for _, todo := range s.Todos {
+			got[len(got)-1].IDs = append(got[len(got)-1].IDs, todo.ID)
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Agenda() = %+v, want %+v", got, expected)
+	}
+}
@@ -0,0 +1,57 @@
+package todotxt
+
+import "time"
+
+// Clock tells the time for the package's date logic. Calendar dates such
+// as "today" are taken in the location of the times it returns, while
+// dates parsed from tasks are midnight UTC; compare them through Today.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct {
+	loc *time.Location
+}
+
+func (c systemClock) Now() time.Time {
+	return time.Now().In(c.loc)
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// SystemClock returns a clock that tells the current time in loc.
+func SystemClock(loc *time.Location) Clock {
+	return systemClock{loc: loc}
+}
+
+// FixedClock returns a clock that is stopped at now, in now's location.
+func FixedClock(now time.Time) Clock {
+	return fixedClock(now)
+}
+
+var clock Clock = SystemClock(time.Local)
+
+// SetClock replaces the clock used by NewTodo, MarkComplete, the Filter
+// functions and queries, and returns the previous one:
+//
+//	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(now)))
+func SetClock(c Clock) Clock {
+	prev := clock
+	clock = c
+	return prev
+}
+
+// Now returns the current time of the clock.
+func Now() time.Time {
+	return clock.Now()
+}
+
+// Today returns the clock's calendar date as midnight UTC, the form of
+// dates parsed from tasks.
+func Today() time.Time {
+	return civilDate(clock.Now())
+}
@@ -0,0 +1,24 @@
+package todotxt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	if loc := SystemClock(jst).Now().Location(); loc != jst {
+		t.Errorf("SystemClock(JST).Now() is in %v", loc)
+	}
+
+	prev := SetClock(FixedClock(time.Date(2025, 1, 10, 8, 0, 0, 0, jst)))
+	defer SetClock(prev)
+
+	if got := Now().UTC().Format(time.RFC3339); got != "2025-01-09T23:00:00Z" {
+		t.Errorf("Now() = %s", got)
+	}
+	if got := Today(); !got.Equal(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Today() = %v, want 2025-01-10 in UTC", got)
+	}
+}
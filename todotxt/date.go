@@ -0,0 +1,118 @@
+package todotxt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseDate parses a date given as an argument or tag value, relative to
+// the day of now:
+//
+//	2025-03-01                ISO date
+//	today, tomorrow, yesterday
+//	mon ... sun, monday ...   the next such day after today
+//	next-mon ...              that day of next week (weeks start on Monday)
+//	next-week, next-month, next-year   the first day of the next period
+//	eow, eom, eoy             the last day of this week, month or year
+//	+3d, -2w, +1m, +1y, +5b   an offset in days, weeks, months, years or business days
+//
+// Words are case-insensitive. The result is midnight UTC, like the dates
+// parsed from tasks.
+func ParseDate(value string, now time.Time) (time.Time, error) {
+	if date, ok := parseDate(value); ok {
+		return date, nil
+	}
+	today := civilDate(now)
+	if date, ok := relativeDate(strings.ToLower(value), today); ok {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD, today, fri, next-mon, eom or an offset such as +3d)", value)
+}
+
+func relativeDate(s string, today time.Time) (time.Time, bool) {
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	year, month, _ := today.Date()
+
+	switch s {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "eow":
+		return monday.AddDate(0, 0, 6), true
+	case "eom":
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC), true
+	case "eoy":
+		return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC), true
+	case "next-week":
+		return monday.AddDate(0, 0, 7), true
+	case "next-month":
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC), true
+	case "next-year":
+		return time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC), true
+	}
+
+	if wd, ok := weekdays[s]; ok {
+		days := (int(wd) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), true
+	}
+	if name, ok := strings.CutPrefix(s, "next-"); ok {
+		if wd, ok := weekdays[name]; ok {
+			return monday.AddDate(0, 0, 7+(int(wd)+6)%7), true
+		}
+	}
+
+	return parseOffset(s, today)
+}
+
+// parseOffset parses a signed offset such as "+3d" or "-1m" from today.
+func parseOffset(s string, today time.Time) (time.Time, bool) {
+	if len(s) < 3 || (s[0] != '+' && s[0] != '-') {
+		return time.Time{}, false
+	}
+	r, err := ParseRecurrence(s[1:])
+	if err != nil || r.Strict {
+		return time.Time{}, false
+	}
+	if s[0] == '-' {
+		r.Interval = -r.Interval
+	}
+	return r.Next(today), true
+}
+
+// ResolveDates replaces relative dates such as "fri" or "+2w" in the
+// date tags of t (see DateTags) with YYYY-MM-DD dates, so that the task
+// keeps its meaning once written. It fails on a value that is not a date.
+func (t *Todo) ResolveDates(now time.Time) error {
+	for _, key := range DateTags {
+		value, ok := t.Tags[key]
+		if !ok {
+			continue
+		}
+		if _, ok := parseDate(value); ok {
+			continue
+		}
+		date, err := ParseDate(value, now)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		t.AddTag(key, date.Format("2006-01-02"))
+	}
+	return nil
+}
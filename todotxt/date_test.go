@@ -0,0 +1,106 @@
+package todotxt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// A Friday evening, late enough to be the next day in UTC.
+	now := time.Date(2025, 1, 10, 23, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "2025-03-01", expected: "2025-03-01"},
+		{input: "+3d", expected: "2025-01-13"},
+		{input: "+2w", expected: "2025-01-24"},
+		{input: "+1m", expected: "2025-02-10"},
+		{input: "+1y", expected: "2026-01-10"},
+		{input: "+1b", expected: "2025-01-13"},
+		{input: "-1d", expected: "2025-01-09"},
+		{input: "-2w", expected: "2024-12-27"},
+		{input: "-1m", expected: "2024-12-10"},
+		{input: "-1b", expected: "2025-01-09"},
+		{input: "today", expected: "2025-01-10"},
+		{input: "Tomorrow", expected: "2025-01-11"},
+		{input: "yesterday", expected: "2025-01-09"},
+		{input: "mon", expected: "2025-01-13"},
+		{input: "thursday", expected: "2025-01-16"},
+		{input: "fri", expected: "2025-01-17"},
+		{input: "sun", expected: "2025-01-12"},
+		{input: "next-mon", expected: "2025-01-13"},
+		{input: "next-fri", expected: "2025-01-17"},
+		{input: "next-sunday", expected: "2025-01-19"},
+		{input: "eow", expected: "2025-01-12"},
+		{input: "eom", expected: "2025-01-31"},
+		{input: "eoy", expected: "2025-12-31"},
+		{input: "next-week", expected: "2025-01-13"},
+		{input: "next-month", expected: "2025-02-01"},
+		{input: "next-year", expected: "2026-01-01"},
+		{input: "3d", wantErr: true},
+		{input: "-+3d", wantErr: true},
+		{input: "next-", wantErr: true},
+		{input: "next-fortnight", wantErr: true},
+		{input: "fr", wantErr: true},
+		{input: "++3d", wantErr: true},
+		{input: "+0d", wantErr: true},
+		{input: "2025-02-30", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			date, err := ParseDate(tt.input, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDate(%q) = %v, want an error", tt.input, date)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDate(%q) failed: %v", tt.input, err)
+			}
+			if got := date.Format("2006-01-02"); got != tt.expected {
+				t.Errorf("ParseDate(%q) = %s, want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTodoResolveDates(t *testing.T) {
+	now := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC) // Wednesday
+
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "Pay rent due:fri", expected: "Pay rent due:2025-01-10"},
+		{input: "Plan trip t:next-mon due:+2w +Travel", expected: "Plan trip t:2025-01-13 due:2025-01-22 +Travel"},
+		{input: "File taxes due:2025-04-15 t:eom", expected: "File taxes due:2025-04-15 t:2025-01-31"},
+		{input: "Call Mom at:tomorrow", expected: "Call Mom at:tomorrow"},
+		{input: "Someday due:someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			todo, _ := ParseTodo(tt.input)
+			err := todo.ResolveDates(now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ResolveDates() = %q, want an error", todo.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveDates() failed: %v", err)
+			}
+			if got := todo.String(); got != tt.expected {
+				t.Errorf("ResolveDates() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
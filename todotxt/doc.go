@@ -0,0 +1,15 @@
+// Package todotxt implements the todo.txt format: parsing and formatting of
+// task lines, reading and writing todo.txt files, and helpers for sorting,
+// filtering and grouping tasks.
+//
+// The todotxt command is a thin CLI on top of this package, so programs
+// importing it see exactly the same parsing rules as the command line.
+//
+//	tf := todotxt.NewTodoFile("todo.txt")
+//	if err := tf.Load(); err != nil {
+//		log.Fatal(err)
+//	}
+//	for _, todo := range tf.FilterByProject("Work") {
+//		fmt.Println(todo.ID, todo.Description)
+//	}
+package todotxt
@@ -0,0 +1,379 @@
+package todotxt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned by Save when the file on disk was changed by
+// someone else since it was loaded or last saved.
+var ErrConflict = errors.New("file was modified externally since it was loaded")
+
+// TodoFile is a todo.txt file. Blank and whitespace-only lines are kept as
+// they are: each stays in front of the task that followed it when the file
+// was loaded, or at the end of the file, and a task's ID is its line
+// number counting them.
+type TodoFile struct {
+	Path  string
+	Todos []*Todo
+
+	blankBefore map[*Todo][]string
+	blankAfter  []string
+
+	lock   *os.File
+	loaded bool
+	state  fileState
+}
+
+// fileState identifies the content of a file at one point in time.
+type fileState struct {
+	exists bool
+	sum    [sha256.Size]byte
+}
+
+func NewTodoFile(path string) *TodoFile {
+	return &TodoFile{
+		Path:  path,
+		Todos: []*Todo{},
+	}
+}
+
+func (tf *TodoFile) Load() error {
+	data, state, err := readFile(tf.Path)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	todos, err := ParseTodos(lines)
+	if err != nil {
+		return fmt.Errorf("failed to parse todos: %w", err)
+	}
+
+	if todos == nil {
+		todos = []*Todo{}
+	}
+
+	tf.blankBefore = make(map[*Todo][]string)
+	tf.blankAfter = nil
+	next := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			next++
+			continue
+		}
+		if next < len(todos) {
+			tf.blankBefore[todos[next]] = append(tf.blankBefore[todos[next]], line)
+		} else {
+			tf.blankAfter = append(tf.blankAfter, line)
+		}
+	}
+
+	tf.Todos = todos
+	tf.loaded = true
+	tf.state = state
+	return nil
+}
+
+func readFile(path string) ([]byte, fileState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fileState{}, nil
+	}
+	if err != nil {
+		return nil, fileState{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, fileState{exists: true, sum: sha256.Sum256(data)}, nil
+}
+
+// Changed reports whether the file on disk differs from what was last
+// loaded or saved. A file that was never loaded is never reported as
+// changed.
+func (tf *TodoFile) Changed() (bool, error) {
+	if !tf.loaded {
+		return false, nil
+	}
+	_, state, err := readFile(tf.Path)
+	if err != nil {
+		return false, err
+	}
+	return state != tf.state, nil
+}
+
+// CheckConflict returns an error wrapping ErrConflict if the file on disk
+// was changed since it was loaded or last saved.
+func (tf *TodoFile) CheckConflict() error {
+	changed, err := tf.Changed()
+	if err != nil {
+		return err
+	}
+	if changed {
+		return fmt.Errorf("%s: %w", tf.Path, ErrConflict)
+	}
+	return nil
+}
+
+// Save writes the todos to a temporary file next to Path, syncs it and
+// renames it over Path, so a crash or a full disk never leaves a truncated
+// todo list behind. If the file was loaded and has since been changed on
+// disk, nothing is written and the error wraps ErrConflict.
+func (tf *TodoFile) Save() error {
+	if err := tf.CheckConflict(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, todo := range tf.Todos {
+		for _, line := range tf.blankBefore[todo] {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(todo.String())
+		buf.WriteByte('\n')
+	}
+	for _, line := range tf.blankAfter {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := writeFileAtomic(tf.Path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	tf.loaded = true
+	tf.state = fileState{exists: true, sum: sha256.Sum256(buf.Bytes())}
+	return nil
+}
+
+// Update runs a locked load, modify, save cycle. If the file changes on
+// disk between loading and saving, fn is applied again to the fresh
+// content, so fn must describe the change rather than replay line numbers.
+func (tf *TodoFile) Update(fn func(*TodoFile) error) error {
+	if tf.lock == nil {
+		if err := tf.Lock(); err != nil {
+			return err
+		}
+		defer tf.Unlock()
+	}
+
+	const attempts = 3
+	for i := 0; ; i++ {
+		if err := tf.Load(); err != nil {
+			return err
+		}
+		if err := fn(tf); err != nil {
+			return err
+		}
+		err := tf.Save()
+		if !errors.Is(err, ErrConflict) || i == attempts-1 {
+			return err
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	// Replace the target of a symlink rather than the link itself.
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write todo: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes a rename durable. It is best effort: not every platform
+// can open or sync a directory.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Add appends todo at the end of the file, after any blank lines there.
+func (tf *TodoFile) Add(todo *Todo) {
+	todo.ID = tf.NextID()
+	if len(tf.blankAfter) > 0 {
+		if tf.blankBefore == nil {
+			tf.blankBefore = make(map[*Todo][]string)
+		}
+		tf.blankBefore[todo] = tf.blankAfter
+		tf.blankAfter = nil
+	}
+	tf.Todos = append(tf.Todos, todo)
+}
+
+// NextID returns the ID Add gives the next task: the line after the end
+// of the file.
+func (tf *TodoFile) NextID() int {
+	if len(tf.Todos) == 0 {
+		return len(tf.blankAfter) + 1
+	}
+	return tf.Todos[len(tf.Todos)-1].ID + len(tf.blankAfter) + 1
+}
+
+// Replace puts todo in the place of old, with its ID and the blank lines
+// before it.
+func (tf *TodoFile) Replace(old, todo *Todo) bool {
+	for i, t := range tf.Todos {
+		if t == old {
+			todo.ID = old.ID
+			tf.Todos[i] = todo
+			if blank, ok := tf.blankBefore[old]; ok {
+				tf.blankBefore[todo] = blank
+				delete(tf.blankBefore, old)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (tf *TodoFile) GetByID(id int) *Todo {
+	for _, todo := range tf.Todos {
+		if todo.ID == id {
+			return todo
+		}
+	}
+	return nil
+}
+
+func (tf *TodoFile) Delete(id int) bool {
+	for _, todo := range tf.Todos {
+		if todo.ID == id {
+			tf.remove(func(t *Todo) bool { return t == todo })
+			return true
+		}
+	}
+	return false
+}
+
+func (tf *TodoFile) RemoveCompleted() []*Todo {
+	return tf.remove(func(t *Todo) bool { return t.Complete })
+}
+
+// remove drops the tasks matching pred and returns them. The blank lines
+// before a removed task stay, in front of the task that follows it.
+func (tf *TodoFile) remove(pred func(*Todo) bool) []*Todo {
+	var removed, remaining []*Todo
+	var blank []string
+	for _, todo := range tf.Todos {
+		blank = append(blank, tf.blankBefore[todo]...)
+		delete(tf.blankBefore, todo)
+		if pred(todo) {
+			removed = append(removed, todo)
+			continue
+		}
+		if len(blank) > 0 {
+			tf.blankBefore[todo] = blank
+			blank = nil
+		}
+		remaining = append(remaining, todo)
+	}
+	tf.blankAfter = append(blank, tf.blankAfter...)
+	tf.Todos = remaining
+	tf.reindexTodos()
+	return removed
+}
+
+// reindexTodos sets every task's ID to its line number.
+func (tf *TodoFile) reindexTodos() {
+	line := 0
+	for _, todo := range tf.Todos {
+		line += len(tf.blankBefore[todo]) + 1
+		todo.ID = line
+	}
+}
+
+func (tf *TodoFile) Search(query string) []*Todo {
+	return tf.Filter(ContainsText(query))
+}
+
+func (tf *TodoFile) FilterByProject(project string) []*Todo {
+	return tf.Filter(HasProject(project))
+}
+
+func (tf *TodoFile) FilterByContext(context string) []*Todo {
+	return tf.Filter(HasContext(context))
+}
+
+func (tf *TodoFile) GetCompleted() []*Todo {
+	var results []*Todo
+	for _, todo := range tf.Todos {
+		if todo.Complete {
+			results = append(results, todo)
+		}
+	}
+	return results
+}
+
+func (tf *TodoFile) GetIncomplete() []*Todo {
+	var results []*Todo
+	for _, todo := range tf.Todos {
+		if !todo.Complete {
+			results = append(results, todo)
+		}
+	}
+	return results
+}
+
+// GetActive returns the incomplete tasks that are not deferred by a t:
+// date after the day of now.
+func (tf *TodoFile) GetActive(now time.Time) []*Todo {
+	return FilterTodos(tf.GetIncomplete(), Not(IsDeferred(now)))
+}
@@ -0,0 +1,531 @@
+package todotxt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTodoFile(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test_todo.txt")
+
+	tf := NewTodoFile(testFile)
+
+	todo1 := NewTodo("First task")
+	todo1.Priority = PriorityA
+	tf.Add(todo1)
+
+	todo2 := NewTodo("Second task")
+	todo2.AddProject("Work")
+	tf.Add(todo2)
+
+	todo3 := NewTodo("Third task")
+	todo3.AddContext("home")
+	tf.Add(todo3)
+
+	if err := tf.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	if _, err := os.Stat(testFile); os.IsNotExist(err) {
+		t.Error("File should exist after save")
+	}
+
+	tf2 := NewTodoFile(testFile)
+	if err := tf2.Load(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if len(tf2.Todos) != 3 {
+		t.Errorf("Expected 3 todos, got %d", len(tf2.Todos))
+	}
+
+	if tf2.Todos[0].Priority != PriorityA {
+		t.Error("First todo should have priority A")
+	}
+
+	if len(tf2.Todos[1].Projects) != 1 || tf2.Todos[1].Projects[0] != "Work" {
+		t.Error("Second todo should have project 'Work'")
+	}
+}
+
+func TestTodoFileGetByID(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+
+	todo1 := NewTodo("Task 1")
+	tf.Add(todo1)
+
+	todo2 := NewTodo("Task 2")
+	tf.Add(todo2)
+
+	found := tf.GetByID(1)
+	if found == nil {
+		t.Fatal("Should find todo with ID 1")
+	}
+	if found.Description != "Task 1" {
+		t.Error("Wrong todo returned")
+	}
+
+	notFound := tf.GetByID(99)
+	if notFound != nil {
+		t.Error("Should return nil for non-existent ID")
+	}
+}
+
+func TestTodoFileDelete(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+
+	todo1 := NewTodo("Task 1")
+	tf.Add(todo1)
+
+	todo2 := NewTodo("Task 2")
+	tf.Add(todo2)
+
+	todo3 := NewTodo("Task 3")
+	tf.Add(todo3)
+
+	if !tf.Delete(2) {
+		t.Error("Delete should return true")
+	}
+
+	if len(tf.Todos) != 2 {
+		t.Errorf("Expected 2 todos after delete, got %d", len(tf.Todos))
+	}
+
+	if tf.Todos[0].ID != 1 || tf.Todos[1].ID != 2 {
+		t.Error("IDs should be reindexed after delete")
+	}
+
+	if tf.Delete(99) {
+		t.Error("Delete should return false for non-existent ID")
+	}
+}
+
+func TestTodoFileSearch(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+
+	todo1 := NewTodo("Buy milk")
+	tf.Add(todo1)
+
+	todo2 := NewTodo("Call mom")
+	todo2.AddProject("Family")
+	tf.Add(todo2)
+
+	todo3 := NewTodo("Write report")
+	todo3.AddContext("work")
+	tf.Add(todo3)
+
+	results := tf.Search("milk")
+	if len(results) != 1 || results[0].Description != "Buy milk" {
+		t.Error("Should find 'Buy milk'")
+	}
+
+	results = tf.Search("family")
+	if len(results) != 1 || results[0].ID != 2 {
+		t.Error("Should find task with Family project")
+	}
+
+	results = tf.Search("work")
+	if len(results) != 1 || results[0].ID != 3 {
+		t.Error("Should find task with work context")
+	}
+
+	results = tf.Search("nonexistent")
+	if len(results) != 0 {
+		t.Error("Should return empty results for non-matching search")
+	}
+}
+
+func TestTodoFileFilters(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+
+	todo1 := NewTodo("Task 1")
+	todo1.AddProject("Work")
+	tf.Add(todo1)
+
+	todo2 := NewTodo("Task 2")
+	todo2.AddProject("Personal")
+	tf.Add(todo2)
+
+	todo3 := NewTodo("Task 3")
+	todo3.AddContext("home")
+	tf.Add(todo3)
+
+	todo4 := NewTodo("Task 4")
+	todo4.Complete = true
+	tf.Add(todo4)
+
+	workTodos := tf.FilterByProject("Work")
+	if len(workTodos) != 1 || workTodos[0].ID != 1 {
+		t.Error("Should filter by project correctly")
+	}
+
+	homeTodos := tf.FilterByContext("home")
+	if len(homeTodos) != 1 || homeTodos[0].ID != 3 {
+		t.Error("Should filter by context correctly")
+	}
+
+	completed := tf.GetCompleted()
+	if len(completed) != 1 || completed[0].ID != 4 {
+		t.Error("Should get completed todos")
+	}
+
+	incomplete := tf.GetIncomplete()
+	if len(incomplete) != 3 {
+		t.Error("Should get incomplete todos")
+	}
+}
+
+func TestTodoFileGetActive(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+	for _, line := range []string{
+		"Open task",
+		"Starts today t:2025-01-10",
+		"Started t:2024-12-01",
+		"Deferred t:2025-01-11",
+		"x 2025-01-09 Done deferred t:2025-02-01",
+		"Bad threshold t:someday",
+	} {
+		todo, _ := ParseTodo(line)
+		tf.Add(todo)
+	}
+
+	// Still January 10 in Tokyo, already January 11 in UTC.
+	now := time.Date(2025, 1, 10, 23, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+
+	var got []int
+	for _, todo := range tf.GetActive(now) {
+		got = append(got, todo.ID)
+	}
+	if want := []int{1, 2, 3, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetActive() IDs = %v, want %v", got, want)
+	}
+
+	deferred := tf.Filter(IsDeferred(now))
+	if len(deferred) != 1 || deferred[0].ID != 4 {
+		t.Errorf("IsDeferred matched %v", deferred)
+	}
+}
+
+func TestTodoFileRemoveCompleted(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+
+	tf.Add(NewTodo("Task 1"))
+	done := NewTodo("Task 2")
+	done.MarkComplete()
+	tf.Add(done)
+	tf.Add(NewTodo("Task 3"))
+
+	removed := tf.RemoveCompleted()
+	if len(removed) != 1 || removed[0].Description != "Task 2" {
+		t.Fatalf("Expected completed task to be removed, got %v", removed)
+	}
+
+	if len(tf.Todos) != 2 {
+		t.Fatalf("Expected 2 todos remaining, got %d", len(tf.Todos))
+	}
+
+	if tf.Todos[1].ID != 2 || tf.Todos[1].Description != "Task 3" {
+		t.Error("Remaining todos should be reindexed")
+	}
+}
+
+func TestTodoFileSavePreservesUnchangedLines(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "todo.txt")
+	original := "(A) Call Mom  +Family z:1 a:2\n" +
+		"2025-01-08 Pay rent due:2025-02-01 @home b:1 a:1\n" +
+		"Write report +Work\n"
+	if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tf := NewTodoFile(testFile)
+	if err := tf.Load(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	tf.GetByID(3).MarkComplete()
+
+	if err := tf.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if lines[0] != "(A) Call Mom  +Family z:1 a:2" {
+		t.Errorf("First line should be unchanged, got %q", lines[0])
+	}
+	if lines[1] != "2025-01-08 Pay rent due:2025-02-01 @home b:1 a:1" {
+		t.Errorf("Second line should be unchanged, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "x ") || !strings.HasSuffix(lines[2], " Write report +Work") {
+		t.Errorf("Third line should be completed, got %q", lines[2])
+	}
+}
+
+func TestTodoFileKeepsBlankLines(t *testing.T) {
+	original := "\n" +
+		"(B) Call Mom +Family\n" +
+		"\n" +
+		"  \n" +
+		"Pay rent\n" +
+		"x 2025-01-09 Water plants\n" +
+		"\t\n" +
+		"Write report +Work\n" +
+		"\n"
+
+	tests := []struct {
+		name     string
+		change   func(*TodoFile)
+		expected string
+		ids      []int
+	}{
+		{name: "Unchanged", change: func(*TodoFile) {}, expected: original, ids: []int{2, 5, 6, 8}},
+		{
+			name:     "Edit",
+			change:   func(tf *TodoFile) { tf.GetByID(5).SetPriority(PriorityA) },
+			expected: strings.Replace(original, "Pay rent", "(A) Pay rent", 1),
+			ids:      []int{2, 5, 6, 8},
+		},
+		{
+			name:     "Delete after blank lines",
+			change:   func(tf *TodoFile) { tf.Delete(5) },
+			expected: "\n(B) Call Mom +Family\n\n  \nx 2025-01-09 Water plants\n\t\nWrite report +Work\n\n",
+			ids:      []int{2, 5, 7},
+		},
+		{
+			name:     "Remove completed",
+			change:   func(tf *TodoFile) { tf.RemoveCompleted() },
+			expected: "\n(B) Call Mom +Family\n\n  \nPay rent\n\t\nWrite report +Work\n\n",
+			ids:      []int{2, 5, 7},
+		},
+		{
+			name:     "Delete the last task",
+			change:   func(tf *TodoFile) { tf.Delete(8) },
+			expected: "\n(B) Call Mom +Family\n\n  \nPay rent\nx 2025-01-09 Water plants\n\t\n\n",
+			ids:      []int{2, 5, 6},
+		},
+		{
+			name:     "Replace",
+			change:   func(tf *TodoFile) { tf.Replace(tf.GetByID(8), parseTestTodos(t, "Write summary")[0]) },
+			expected: strings.Replace(original, "Write report +Work", "Write summary", 1),
+			ids:      []int{2, 5, 6, 8},
+		},
+		{
+			name:     "Add",
+			change:   func(tf *TodoFile) { tf.Add(parseTestTodos(t, "Buy milk")[0]) },
+			expected: original + "Buy milk\n",
+			ids:      []int{2, 5, 6, 8, 10},
+		},
+	}
+
+	defer SetClock(SetClock(FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "todo.txt")
+			if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tf := NewTodoFile(testFile)
+			if err := tf.Load(); err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+
+			tt.change(tf)
+			if err := tf.Save(); err != nil {
+				t.Fatalf("Failed to save: %v", err)
+			}
+
+			data, err := os.ReadFile(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Saved %q, want %q", data, tt.expected)
+			}
+			var ids []int
+			for _, todo := range tf.Todos {
+				ids = append(ids, todo.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("IDs = %v, want %v", ids, tt.ids)
+			}
+			if err := tf.Load(); err != nil {
+				t.Fatalf("Failed to reload: %v", err)
+			}
+			for i, todo := range tf.Todos {
+				if todo.ID != tt.ids[i] {
+					t.Errorf("Reloaded task %q has ID %d, want %d", todo.String(), todo.ID, tt.ids[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTodoFileSaveIsAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "todo.txt")
+	if err := os.WriteFile(testFile, []byte("Old task\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tf := NewTodoFile(testFile)
+	if err := tf.Load(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	tf.Add(NewTodo("New task"))
+
+	if err := tf.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Temporary files should be cleaned up, found %d entries", len(entries))
+	}
+
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Save should keep file permissions, got %v", info.Mode().Perm())
+	}
+}
+
+func TestTodoFileSaveFollowsSymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "sync", "todo.txt")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("Old task\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tempDir, "todo.txt")
+	if err := os.Symlink(filepath.Join("sync", "todo.txt"), link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	tf := NewTodoFile(link)
+	if err := tf.Load(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	tf.Add(NewTodo("New task"))
+	if err := tf.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Save replaced the symlink with a regular file")
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "Old task\n") || !strings.Contains(string(data), "New task") {
+		t.Errorf("Save did not write through the symlink, target is %q", data)
+	}
+	if info, err := os.Stat(target); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("Save should keep the target's permissions, got %v", info.Mode().Perm())
+	}
+}
+
+func TestTodoFileSaveDetectsConflict(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(testFile, []byte("Task 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tf := NewTodoFile(testFile)
+	if err := tf.Load(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if changed, err := tf.Changed(); err != nil || changed {
+		t.Fatalf("File should not be changed after load, got %v, %v", changed, err)
+	}
+
+	if err := os.WriteFile(testFile, []byte("Task 1\nAdded in editor\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := tf.Changed(); err != nil || !changed {
+		t.Fatalf("File should be changed after external write, got %v, %v", changed, err)
+	}
+
+	tf.Add(NewTodo("Task 2"))
+	err := tf.Save()
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	data, _ := os.ReadFile(testFile)
+	if string(data) != "Task 1\nAdded in editor\n" {
+		t.Errorf("Conflicting save should not write, file is %q", data)
+	}
+
+	if err := tf.Load(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	tf.Add(NewTodo("Task 2"))
+	if err := tf.Save(); err != nil {
+		t.Fatalf("Save after reload should succeed, got %v", err)
+	}
+
+	if err := tf.Save(); err != nil {
+		t.Fatalf("Saving twice should not conflict, got %v", err)
+	}
+}
+
+func TestTodoFileUpdateReappliesOnConflict(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(testFile, []byte("Task 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tf := NewTodoFile(testFile)
+	calls := 0
+	err := tf.Update(func(tf *TodoFile) error {
+		calls++
+		if calls == 1 {
+			// Simulate an editor saving between load and save.
+			if err := os.WriteFile(testFile, []byte("Task 1\nFrom editor\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tf.Add(NewTodo("From update"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected the mutation to be applied twice, got %d", calls)
+	}
+
+	data, _ := os.ReadFile(testFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[1] != "From editor" || !strings.HasSuffix(lines[2], "From update") {
+		t.Errorf("Update should keep the external edit, file is %q", data)
+	}
+}
@@ -0,0 +1,189 @@
+package todotxt
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ICalProductID identifies this package in the PRODID of exported
+// calendars.
+const ICalProductID = "-//kuniyoshi//todotxt//EN"
+
+// icalLineLimit is the longest content line, in octets, before it is
+// folded (RFC 5545 section 3.1).
+const icalLineLimit = 75
+
+// ICalUID returns the UID a task is exported with. Tasks with an id: tag
+// use it, so their UID never changes, and tasks imported from a calendar
+// keep the UID of their uid: tag. Other tasks get a hash of their
+// creation date and description, which stays the same while the task's
+// priority, projects, contexts, tags or completion change.
+func ICalUID(t *Todo) string {
+	if id := t.StableID(); id != "" {
+		return id + "@todotxt"
+	}
+	if uid := t.Tags[ICalUIDTag]; uid != "" {
+		return uid
+	}
+
+	h := sha256.New()
+	if t.CreationDate != nil {
+		h.Write([]byte(t.CreationDate.Format("2006-01-02")))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(t.Description))
+	return hex.EncodeToString(h.Sum(nil)[:12]) + "@todotxt"
+}
+
+// ICalUIDs returns the UIDs todos are exported with in one calendar. It is
+// ICalUID for each task, except that tasks sharing a UID with an earlier
+// one, such as two identical tasks without an id: tag, get "-2", "-3" and
+// so on added to it so every UID in the calendar is unique.
+func ICalUIDs(todos []*Todo) []string {
+	uids := make([]string, len(todos))
+	seen := make(map[string]bool)
+	for i, todo := range todos {
+		base := ICalUID(todo)
+		uid := base
+		for n := 2; seen[uid]; n++ {
+			local, domain, _ := strings.Cut(base, "@")
+			uid = fmt.Sprintf("%s-%d", local, n)
+			if domain != "" {
+				uid += "@" + domain
+			}
+		}
+		seen[uid] = true
+		uids[i] = uid
+	}
+	return uids
+}
+
+// ICalPriority maps a todo.txt priority onto the iCalendar PRIORITY scale,
+// where 1 is the highest and 0 means undefined: A-H become 1-8 and I-Z
+// become 9.
+func ICalPriority(p Priority) int {
+	if p < 'A' || p > 'Z' {
+		return 0
+	}
+	if p >= 'I' {
+		return 9
+	}
+	return int(p-'A') + 1
+}
+
+// WriteICal writes todos as an RFC 5545 calendar with one VTODO each. now
+// is used for DTSTAMP. Projects and contexts are written to CATEGORIES
+// with their + and @ prefixes; due: becomes DUE and t: becomes DTSTART.
+func WriteICal(w io.Writer, todos []*Todo, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	iw := &icalWriter{w: bw}
+
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:" + ICalProductID)
+	iw.line("CALSCALE:GREGORIAN")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	uids := ICalUIDs(todos)
+	for i, todo := range todos {
+		iw.line("BEGIN:VTODO")
+		iw.line("UID:" + uids[i])
+		iw.line("DTSTAMP:" + stamp)
+		iw.line("SUMMARY:" + icalEscape(todo.Description))
+
+		if todo.CreationDate != nil {
+			iw.line("CREATED:" + icalDateTime(*todo.CreationDate))
+		}
+		due, hasDue := parseDate(todo.Tags["due"])
+		// DUE must be later than DTSTART, so a t: date on or after the due
+		// date is left out.
+		if start, ok := parseDate(todo.Tags["t"]); ok && (!hasDue || start.Before(due)) {
+			iw.line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		}
+		if hasDue {
+			iw.line("DUE;VALUE=DATE:" + due.Format("20060102"))
+		}
+		if p := ICalPriority(todo.Priority); p != 0 {
+			iw.line(fmt.Sprintf("PRIORITY:%d", p))
+		}
+
+		var categories []string
+		for _, project := range todo.Projects {
+			categories = append(categories, icalEscape("+"+project))
+		}
+		for _, context := range todo.Contexts {
+			categories = append(categories, icalEscape("@"+context))
+		}
+		if len(categories) > 0 {
+			iw.line("CATEGORIES:" + strings.Join(categories, ","))
+		}
+
+		if todo.Complete {
+			iw.line("STATUS:COMPLETED")
+			if todo.CompletionDate != nil {
+				iw.line("COMPLETED:" + icalDateTime(*todo.CompletionDate))
+			}
+		} else {
+			iw.line("STATUS:NEEDS-ACTION")
+		}
+
+		iw.line("DESCRIPTION:" + icalEscape(todo.String()))
+		iw.line("END:VTODO")
+	}
+
+	iw.line("END:VCALENDAR")
+
+	if iw.err == nil {
+		iw.err = bw.Flush()
+	}
+	if iw.err != nil {
+		return fmt.Errorf("failed to write calendar: %w", iw.err)
+	}
+	return nil
+}
+
+type icalWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folding it so no physical line exceeds
+// icalLineLimit octets. Folds never split a UTF-8 sequence.
+func (iw *icalWriter) line(s string) {
+	if iw.err != nil {
+		return
+	}
+
+	limit := icalLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, iw.err = iw.w.WriteString(s[:cut] + "\r\n "); iw.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = icalLineLimit - 1
+	}
+	_, iw.err = iw.w.WriteString(s + "\r\n")
+}
+
+// icalDateTime formats a todo.txt date as midnight UTC, since CREATED and
+// COMPLETED must be UTC date-times.
+func icalDateTime(date time.Time) string {
+	return date.Format("20060102") + "T000000Z"
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
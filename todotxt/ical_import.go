@@ -0,0 +1,529 @@
+package todotxt
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	// TZIDs must resolve even where the system has no zoneinfo.
+	_ "time/tzdata"
+)
+
+// ICalUIDTag is the tag that links an imported task to the UID of the
+// calendar component it came from.
+const ICalUIDTag = "uid"
+
+// ICalImportOptions controls ReadICal.
+type ICalImportOptions struct {
+	// Events also turns VEVENTs into "Prepare for" tasks due on the day the
+	// event starts.
+	Events bool
+	// Location is the time zone date-times are converted to before their
+	// date is taken. Nil means the location of the package clock.
+	Location *time.Location
+}
+
+// ICalTask is a task read from a calendar component.
+type ICalTask struct {
+	UID  string
+	Todo *Todo
+}
+
+// icalProperty is one unfolded content line.
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+type icalComponent struct {
+	kind  string
+	line  int
+	props []icalProperty
+}
+
+func (c *icalComponent) get(name string) *icalProperty {
+	for i := range c.props {
+		if c.props[i].name == name {
+			return &c.props[i]
+		}
+	}
+	return nil
+}
+
+// ReadICal reads VTODOs, and VEVENTs if opts.Events is set, from an RFC
+// 5545 calendar. Folded lines are joined, date-times with a TZID or in UTC
+// are converted to opts.Location, and RRULEs become strict rec: tags.
+// Components that cannot be turned into a task are returned as RowErrors
+// numbered by the line of their BEGIN; cancelled tasks and recurrence
+// overrides are skipped.
+func ReadICal(r io.Reader, opts ICalImportOptions) ([]ICalTask, []*RowError, error) {
+	if opts.Location == nil {
+		opts.Location = Now().Location()
+	}
+
+	components, err := readICalComponents(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tasks []ICalTask
+	var rowErrors []*RowError
+	for _, c := range components {
+		if c.kind == "VEVENT" && !opts.Events {
+			continue
+		}
+		if c.get("RECURRENCE-ID") != nil {
+			continue
+		}
+		if status := c.get("STATUS"); status != nil && strings.EqualFold(status.value, "CANCELLED") {
+			continue
+		}
+
+		task, err := icalTask(c, opts.Location)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Row: c.line, Err: err})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rowErrors, nil
+}
+
+// readICalComponents unfolds and parses the content lines of every VTODO
+// and VEVENT. Properties of nested components such as VALARM are left out.
+func readICalComponents(r io.Reader) ([]*icalComponent, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var components []*icalComponent
+	var current *icalComponent
+	var stack []string
+
+	var logical string
+	logicalLine, lineNo := 0, 0
+	flush := func() error {
+		if logical == "" {
+			return nil
+		}
+		prop, err := parseICalLine(logical)
+		logical = ""
+		if err != nil {
+			return fmt.Errorf("line %d: %w", logicalLine, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			kind := strings.ToUpper(prop.value)
+			stack = append(stack, kind)
+			if (kind == "VTODO" || kind == "VEVENT") && current == nil {
+				current = &icalComponent{kind: kind, line: logicalLine}
+			}
+			return nil
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(prop.value) {
+				return fmt.Errorf("line %d: unexpected END:%s", logicalLine, prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			if current != nil && len(stack) > 0 && stack[len(stack)-1] == "VCALENDAR" {
+				components = append(components, current)
+				current = nil
+			}
+			return nil
+		}
+
+		if current != nil && len(stack) > 0 && stack[len(stack)-1] == current.kind {
+			current.props = append(current.props, prop)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			logical += line[1:]
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		logical, logicalLine = line, lineNo
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("calendar ends inside %s", stack[len(stack)-1])
+	}
+	return components, nil
+}
+
+// parseICalLine splits name;param=value;...:value, allowing quoted
+// parameter values to contain ';' and ':'.
+func parseICalLine(line string) (icalProperty, error) {
+	prop := icalProperty{params: make(map[string]string)}
+
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return prop, fmt.Errorf("malformed content line %q", line)
+	}
+	prop.name = strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return prop, fmt.Errorf("malformed parameter in %q", line)
+		}
+		name := strings.ToUpper(rest[:eq])
+		j := eq + 1
+		var value string
+		if j < len(rest) && rest[j] == '"' {
+			end := strings.IndexByte(rest[j+1:], '"')
+			if end < 0 {
+				return prop, fmt.Errorf("unterminated quote in %q", line)
+			}
+			value = rest[j+1 : j+1+end]
+			j += end + 2
+		} else {
+			end := strings.IndexAny(rest[j:], ";:")
+			if end < 0 {
+				return prop, fmt.Errorf("malformed content line %q", line)
+			}
+			value = rest[j : j+end]
+			j += end
+		}
+		prop.params[name] = value
+		i += 1 + j
+		if i >= len(line) {
+			return prop, fmt.Errorf("malformed content line %q", line)
+		}
+	}
+
+	prop.value = line[i+1:]
+	return prop, nil
+}
+
+func icalTask(c *icalComponent, loc *time.Location) (ICalTask, error) {
+	task := ICalTask{}
+	if uid := c.get("UID"); uid != nil {
+		task.UID = uid.value
+	}
+
+	r := Record{Tags: make(map[string]string)}
+
+	summary := ""
+	if p := c.get("SUMMARY"); p != nil {
+		summary = strings.Join(strings.Fields(icalUnescape(p.value)), " ")
+	}
+	if summary == "" {
+		summary = "Untitled"
+	}
+
+	for _, p := range c.props {
+		if p.name != "CATEGORIES" {
+			continue
+		}
+		for _, category := range icalSplitList(p.value) {
+			category = strings.Join(strings.Fields(category), "-")
+			switch {
+			case strings.HasPrefix(category, "@") && len(category) > 1:
+				r.Contexts = append(r.Contexts, category)
+			case strings.HasPrefix(category, "+") && len(category) > 1:
+				r.Projects = append(r.Projects, category)
+			case category != "" && category != "+" && category != "@":
+				r.Projects = append(r.Projects, category)
+			}
+		}
+	}
+
+	if rule := c.get("RRULE"); rule != nil {
+		if rec := icalRecurrence(rule.value); rec != "" {
+			r.Tags["rec"] = rec
+		}
+	}
+
+	if task.UID != "" && !strings.HasSuffix(task.UID, "@todotxt") {
+		r.Tags[ICalUIDTag] = icalUIDTagValue(task.UID)
+	}
+
+	if c.kind == "VEVENT" {
+		r.Description = "Prepare for " + summary
+		start, err := icalPropertyDate(c.get("DTSTART"), loc)
+		if err != nil {
+			return task, fmt.Errorf("DTSTART: %w", err)
+		}
+		if start != "" {
+			r.Tags["due"] = start
+		}
+	} else {
+		r.Description = summary
+
+		if p := c.get("PRIORITY"); p != nil {
+			n, err := strconv.Atoi(strings.TrimSpace(p.value))
+			if err != nil || n < 0 || n > 9 {
+				return task, fmt.Errorf("PRIORITY: %q is not 0-9", p.value)
+			}
+			if n > 0 {
+				r.Priority = string(rune('A' + n - 1))
+			}
+		}
+
+		for name, tag := range map[string]string{"DUE": "due", "DTSTART": "t"} {
+			date, err := icalPropertyDate(c.get(name), loc)
+			if err != nil {
+				return task, fmt.Errorf("%s: %w", name, err)
+			}
+			if date != "" {
+				r.Tags[tag] = date
+			}
+		}
+
+		created, err := icalPropertyDate(c.get("CREATED"), loc)
+		if err != nil {
+			return task, fmt.Errorf("CREATED: %w", err)
+		}
+		r.CreationDate = created
+
+		completed, err := icalPropertyDate(c.get("COMPLETED"), loc)
+		if err != nil {
+			return task, fmt.Errorf("COMPLETED: %w", err)
+		}
+		status := c.get("STATUS")
+		if completed != "" || status != nil && strings.EqualFold(status.value, "COMPLETED") {
+			r.Complete = true
+			r.Priority = ""
+			r.CompletionDate = completed
+			if completed == "" {
+				// A completed task can only keep its creation date
+				// alongside a completion date.
+				r.CreationDate = ""
+			}
+		}
+	}
+
+	todo, err := r.Todo()
+	if err != nil {
+		return task, err
+	}
+	task.Todo = todo
+	return task, nil
+}
+
+// icalPropertyDate returns the todo.txt date of a DATE or DATE-TIME value,
+// or "" if p is nil.
+func icalPropertyDate(p *icalProperty, loc *time.Location) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+	value := strings.TrimSpace(p.value)
+
+	if len(value) == len("20060102") {
+		date, err := time.Parse("20060102", value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a date", value)
+		}
+		return date.Format("2006-01-02"), nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a date-time", value)
+		}
+		return t.In(loc).Format("2006-01-02"), nil
+	}
+
+	// Floating times keep their wall clock; times with an unknown TZID
+	// are treated as floating.
+	zone := loc
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			zone = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, zone)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a date-time", value)
+	}
+	return t.In(loc).Format("2006-01-02"), nil
+}
+
+// icalRecurrence maps an RRULE onto a strict rec: value such as +1w, or
+// "" when the rule has no equivalent. Weekday-only daily or weekly rules
+// become business-day recurrences.
+func icalRecurrence(rule string) string {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	interval := 1
+	if v, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return ""
+		}
+		interval = n
+	}
+
+	unit := map[string]string{"DAILY": "d", "WEEKLY": "w", "MONTHLY": "m", "YEARLY": "y"}[parts["FREQ"]]
+	if unit == "" {
+		return ""
+	}
+	if parts["BYDAY"] == "MO,TU,WE,TH,FR" && interval == 1 && (unit == "d" || unit == "w") {
+		unit = "b"
+	}
+	return "+" + strconv.Itoa(interval) + unit
+}
+
+// icalUIDTagValue returns uid as it is stored in a uid: tag. UIDs that
+// are not valid tag values are replaced by a hash.
+func icalUIDTagValue(uid string) string {
+	if _, _, ok := splitTag(ICalUIDTag + ":" + uid); ok && !strings.ContainsFunc(uid, isSpaceRune) {
+		return uid
+	}
+	sum := sha256.Sum256([]byte(uid))
+	return hex.EncodeToString(sum[:12])
+}
+
+// icalSplitList splits a comma-separated value on unescaped commas and
+// unescapes each item.
+func icalSplitList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, icalUnescape(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, icalUnescape(value[start:]))
+}
+
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func icalUnescape(s string) string {
+	return icalUnescaper.Replace(s)
+}
+
+// MatchICal returns the task imported from, or exported as, uid.
+func (tf *TodoFile) MatchICal(uid string) *Todo {
+	if uid == "" {
+		return nil
+	}
+	tag := icalUIDTagValue(uid)
+	uids := ICalUIDs(tf.Todos)
+	for i, todo := range tf.Todos {
+		if todo.Tags[ICalUIDTag] == tag || uids[i] == uid {
+			return todo
+		}
+	}
+	return nil
+}
+
+// ImportICal adds tasks that are new and updates the tasks earlier imports
+// or exports created for the same UID, returning both. Updates replace the
+// description, priority, completion, projects, contexts and the due:, t:
+// and rec: tags, keeping the task's other tags and its place in the file.
+//
+// New tasks are added with add, or tf.Add if it is nil. A task exported by
+// todotxt gets back the id: tag its UID was made from, or a uid: tag with
+// its hash UID, so that importing the same calendar again updates it.
+// Likewise an updated task without an id: tag is given a uid: tag, because
+// the hash UID it was exported with changes along with its description.
+func (tf *TodoFile) ImportICal(tasks []ICalTask, add func(*Todo) error) (added, updated []*Todo, err error) {
+	for _, task := range tasks {
+		existing := tf.MatchICal(task.UID)
+		if existing == nil {
+			if id, ok := exportedStableID(task.UID); ok {
+				task.Todo.AddTag(IDTag, id)
+			} else if _, ok := task.Todo.Tags[ICalUIDTag]; !ok && task.UID != "" {
+				task.Todo.AddTag(ICalUIDTag, icalUIDTagValue(task.UID))
+			}
+			if add == nil {
+				tf.Add(task.Todo)
+			} else if err := add(task.Todo); err != nil {
+				return added, updated, err
+			}
+			added = append(added, task.Todo)
+			continue
+		}
+		if updateFromICal(existing, task.Todo) {
+			if _, ok := existing.Tags[ICalUIDTag]; !ok && existing.StableID() == "" {
+				existing.AddTag(ICalUIDTag, icalUIDTagValue(task.UID))
+			}
+			updated = append(updated, existing)
+		}
+	}
+	return added, updated, nil
+}
+
+// exportedStableID returns the id: tag value an exported UID was made
+// from, as opposed to the hash ICalUID falls back to.
+func exportedStableID(uid string) (string, bool) {
+	local, ok := strings.CutSuffix(uid, "@todotxt")
+	if !ok || local == "" || isExportHash(local) {
+		return "", false
+	}
+	if _, _, ok := splitTag(IDTag + ":" + local); !ok || strings.ContainsFunc(local, isSpaceRune) {
+		return "", false
+	}
+	return local, true
+}
+
+// isExportHash reports whether local is the part of a hash UID before
+// "@todotxt", with or without the suffix ICalUIDs adds to duplicates.
+func isExportHash(local string) bool {
+	hash, _, _ := strings.Cut(local, "-")
+	_, err := hex.DecodeString(hash)
+	return len(hash) == 24 && err == nil
+}
+
+func updateFromICal(t, from *Todo) bool {
+	before := t.String()
+
+	t.Description = from.Description
+	if from.Complete {
+		if !t.Complete || from.CompletionDate != nil {
+			t.Complete = true
+			t.CompletionDate = from.CompletionDate
+		}
+		t.Priority = PriorityNone
+	} else {
+		t.MarkUncomplete()
+		// PRIORITY 9 stands for I-Z; keep a priority that maps to the same
+		// value.
+		if ICalPriority(t.Priority) != ICalPriority(from.Priority) {
+			t.Priority = from.Priority
+		}
+	}
+	if from.CreationDate != nil {
+		t.CreationDate = from.CreationDate
+	}
+	t.Projects = append([]string{}, from.Projects...)
+	t.Contexts = append([]string{}, from.Contexts...)
+
+	for _, key := range []string{"due", "t", "rec", ICalUIDTag} {
+		if value, ok := from.Tags[key]; ok {
+			t.Tags[key] = value
+		} else if key != ICalUIDTag {
+			delete(t.Tags, key)
+		}
+	}
+
+	return t.String() != before
+}
@@ -0,0 +1,270 @@
+package todotxt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const icalTestCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Calendar//EN\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-1@example.com\r\n" +
+	"SUMMARY:Write the quarterly report for the board\\, including the appendix \r\n" +
+	" and charts\r\n" +
+	"PRIORITY:2\r\n" +
+	"CATEGORIES:+Work,@office,Big Plans\r\n" +
+	"DUE;TZID=America/New_York:20250115T220000\r\n" +
+	"CREATED:20250108T120000Z\r\n" +
+	"RRULE:FREQ=MONTHLY;INTERVAL=3\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"SUMMARY:Alarm summary\r\n" +
+	"END:VALARM\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-2@example.com\r\n" +
+	"SUMMARY:Buy milk\r\n" +
+	"STATUS:COMPLETED\r\n" +
+	"COMPLETED:20250109T080000Z\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-3@example.com\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-4@example.com\r\n" +
+	"SUMMARY:Broken\r\n" +
+	"PRIORITY:high\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:event-1@example.com\r\n" +
+	"SUMMARY:Team sync\r\n" +
+	"DTSTART;TZID=\"Asia/Tokyo\":20250120T080000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestReadICal(t *testing.T) {
+	tasks, rowErrors, err := ReadICal(strings.NewReader(icalTestCalendar), ICalImportOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"(B) 2025-01-08 Write the quarterly report for the board, including the appendix and charts +Work +Big-Plans @office due:2025-01-16 rec:+3m uid:task-1@example.com",
+		"x 2025-01-09 Buy milk uid:task-2@example.com",
+	}
+	if len(tasks) != len(expected) {
+		t.Fatalf("Expected %d tasks, got %d", len(expected), len(tasks))
+	}
+	for i, want := range expected {
+		if got := tasks[i].Todo.String(); got != want {
+			t.Errorf("Task %d:\nexpected %q\ngot      %q", i, want, got)
+		}
+	}
+	if tasks[0].UID != "task-1@example.com" {
+		t.Errorf("Unexpected UID: %s", tasks[0].UID)
+	}
+
+	if len(rowErrors) != 1 || rowErrors[0].Row != 29 || !strings.Contains(rowErrors[0].Error(), "PRIORITY") {
+		t.Errorf("Expected a PRIORITY error at line 29, got %v", rowErrors)
+	}
+}
+
+func TestReadICalEvents(t *testing.T) {
+	tasks, _, err := ReadICal(strings.NewReader(icalTestCalendar), ICalImportOptions{Events: true, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	last := tasks[len(tasks)-1].Todo.String()
+	if last != "Prepare for Team sync due:2025-01-19 rec:+1b uid:event-1@example.com" {
+		t.Errorf("Unexpected event task: %q", last)
+	}
+}
+
+func TestReadICalMalformed(t *testing.T) {
+	tests := []string{
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:x\r\n",
+		"BEGIN:VCALENDAR\r\nnot a content line\r\nEND:VCALENDAR\r\n",
+	}
+	for _, input := range tests {
+		if _, _, err := ReadICal(strings.NewReader(input), ICalImportOptions{}); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestICalRecurrence(t *testing.T) {
+	tests := map[string]string{
+		"FREQ=DAILY":                            "+1d",
+		"FREQ=WEEKLY;INTERVAL=2":                "+2w",
+		"FREQ=YEARLY":                           "+1y",
+		"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR":       "+1b",
+		"FREQ=HOURLY":                           "",
+		"FREQ=MONTHLY;INTERVAL=0":               "",
+		"freq=monthly;interval=6;bymonthday=15": "+6m",
+	}
+	for rule, want := range tests {
+		if got := icalRecurrence(rule); got != want {
+			t.Errorf("icalRecurrence(%q) = %q, expected %q", rule, got, want)
+		}
+	}
+}
+
+func TestImportICalUpdatesByUID(t *testing.T) {
+	tf := NewTodoFile("")
+	first, _, err := ReadICal(strings.NewReader(icalTestCalendar), ICalImportOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	added, updated, _ := tf.ImportICal(first, nil)
+	if len(added) != 2 || len(updated) != 0 {
+		t.Fatalf("Expected 2 added, got %d added and %d updated", len(added), len(updated))
+	}
+	tf.Todos[0].AddTag("id", "k3m9qa")
+
+	again, _, _ := ReadICal(strings.NewReader(icalTestCalendar), ICalImportOptions{Location: time.UTC})
+	added, updated, _ = tf.ImportICal(again, nil)
+	if len(added) != 0 || len(updated) != 0 {
+		t.Fatalf("Re-import should change nothing, got %d added and %d updated", len(added), len(updated))
+	}
+
+	changed := strings.Replace(icalTestCalendar, "PRIORITY:2", "PRIORITY:1", 1)
+	changed = strings.Replace(changed, "RRULE:FREQ=MONTHLY;INTERVAL=3\r\n", "", 1)
+	tasks, _, _ := ReadICal(strings.NewReader(changed), ICalImportOptions{Location: time.UTC})
+	added, updated, _ = tf.ImportICal(tasks, nil)
+	if len(added) != 0 || len(updated) != 1 {
+		t.Fatalf("Expected 1 update, got %d added and %d updated", len(added), len(updated))
+	}
+
+	todo := tf.Todos[0]
+	if todo.Priority != 'A' || todo.Tags["rec"] != "" || todo.Tags["id"] != "k3m9qa" {
+		t.Errorf("Unexpected updated task: %s", todo.String())
+	}
+	if len(tf.Todos) != 2 {
+		t.Errorf("Expected 2 tasks, got %d", len(tf.Todos))
+	}
+}
+
+func TestImportICalRepeatedAfterCalendarEdit(t *testing.T) {
+	tf := NewTodoFile("")
+	tf.Todos, _ = ParseTodos([]string{"2025-01-08 Call Mom +Family due:2025-01-15"})
+
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, tf.Todos, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	uid := ICalUID(tf.Todos[0])
+	edited := strings.Replace(buf.String(), "SUMMARY:Call Mom", "SUMMARY:Call Mom about the trip", 1)
+
+	for i, expected := range []int{1, 0, 0} {
+		tasks, _, err := ReadICal(strings.NewReader(edited), ICalImportOptions{Location: time.UTC})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		added, updated, _ := tf.ImportICal(tasks, nil)
+		if len(added) != 0 || len(updated) != expected {
+			t.Fatalf("Import %d: expected %d updated, got %d added and %d updated", i+1, expected, len(added), len(updated))
+		}
+	}
+
+	if len(tf.Todos) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(tf.Todos))
+	}
+	todo := tf.Todos[0]
+	if todo.Description != "Call Mom about the trip" || todo.Tags[ICalUIDTag] != uid {
+		t.Errorf("Unexpected updated task: %s", todo.String())
+	}
+	if got := ICalUID(todo); got != uid {
+		t.Errorf("UID changed from %s to %s", uid, got)
+	}
+}
+
+func TestImportICalIdenticalTasks(t *testing.T) {
+	tf := NewTodoFile("")
+	tf.Todos, _ = ParseTodos([]string{"2025-01-08 Water plants", "2025-01-08 Water plants"})
+
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, tf.Todos, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	uids := ICalUIDs(tf.Todos)
+	second := strings.Index(buf.String(), "UID:"+uids[1])
+	if second < 0 {
+		t.Fatalf("Expected UID %s in the calendar", uids[1])
+	}
+	edited := buf.String()[:second] + strings.Replace(buf.String()[second:], "SUMMARY:Water plants", "SUMMARY:Water the balcony plants", 1)
+
+	tasks, _, err := ReadICal(strings.NewReader(edited), ICalImportOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	added, updated, _ := tf.ImportICal(tasks, nil)
+	if len(added) != 0 || len(updated) != 1 || updated[0] != tf.Todos[1] {
+		t.Fatalf("Expected only the second task updated, got %d added and %d updated", len(added), len(updated))
+	}
+	if tf.Todos[0].Description != "Water plants" || tf.Todos[1].Description != "Water the balcony plants" {
+		t.Errorf("Unexpected tasks: %q, %q", tf.Todos[0].String(), tf.Todos[1].String())
+	}
+}
+
+func TestImportICalRoundTrip(t *testing.T) {
+	tf := NewTodoFile("")
+	todos, _ := ParseTodos([]string{
+		"(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15",
+		"(Q) Pay rent id:k3m9qa",
+	})
+	tf.Todos = todos
+
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, tf.Todos, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tasks, rowErrors, err := ReadICal(&buf, ICalImportOptions{Location: time.UTC})
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("Unexpected errors: %v %v", err, rowErrors)
+	}
+	added, updated, _ := tf.ImportICal(tasks, nil)
+	if len(added) != 0 || len(updated) != 0 {
+		t.Errorf("Importing an export should match every task, got %d added and %d updated", len(added), len(updated))
+	}
+}
+
+func TestImportICalExportIntoAnotherFile(t *testing.T) {
+	source := parseTestTodos(t,
+		"Task one +Work id:k3m9qa",
+		"2025-01-08 Task two",
+		"2025-01-08 Task two",
+	)
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, source, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tf := NewTodoFile("")
+	for i, expected := range []int{3, 0} {
+		tasks, _, err := ReadICal(strings.NewReader(buf.String()), ICalImportOptions{Location: time.UTC})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		added, updated, err := tf.ImportICal(tasks, nil)
+		if err != nil || len(added) != expected || len(updated) != 0 {
+			t.Fatalf("Import %d: expected %d added, got %d added and %d updated (%v)", i+1, expected, len(added), len(updated), err)
+		}
+	}
+
+	if got := tf.Todos[0].String(); got != "Task one +Work id:k3m9qa" {
+		t.Errorf("Expected the id: tag back, got %q", got)
+	}
+	if got, expected := ICalUIDs(tf.Todos), ICalUIDs(source); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Imported tasks export as %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,159 @@
+package todotxt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestICalPriority(t *testing.T) {
+	tests := []struct {
+		priority Priority
+		expected int
+	}{
+		{PriorityNone, 0},
+		{'A', 1},
+		{'C', 3},
+		{'H', 8},
+		{'I', 9},
+		{'Z', 9},
+	}
+
+	for _, tt := range tests {
+		if got := ICalPriority(tt.priority); got != tt.expected {
+			t.Errorf("ICalPriority(%q) = %d, expected %d", tt.priority, got, tt.expected)
+		}
+	}
+}
+
+func TestICalUIDStable(t *testing.T) {
+	todo, _ := ParseTodo("(A) 2025-01-08 Call Mom +Family @phone")
+	uid := ICalUID(todo)
+
+	todo.SetPriority('B')
+	todo.AddContext("home")
+	todo.MarkComplete()
+	if got := ICalUID(todo); got != uid {
+		t.Errorf("UID changed from %s to %s", uid, got)
+	}
+
+	other, _ := ParseTodo("2025-01-08 Call Dad")
+	if ICalUID(other) == uid {
+		t.Error("Different tasks should have different UIDs")
+	}
+
+	withID, _ := ParseTodo("Call Mom id:k3m9qa")
+	if got := ICalUID(withID); got != "k3m9qa@todotxt" {
+		t.Errorf("Expected the id: tag in the UID, got %s", got)
+	}
+}
+
+func TestICalUIDsUnique(t *testing.T) {
+	todos, _ := ParseTodos([]string{
+		"2025-01-08 Water plants",
+		"Call Mom id:k3m9qa",
+		"2025-01-08 Water plants",
+		"(B) 2025-01-08 Water plants @home",
+	})
+
+	uids := ICalUIDs(todos)
+	base := ICalUID(todos[0])
+	local := strings.TrimSuffix(base, "@todotxt")
+	expected := []string{base, "k3m9qa@todotxt", local + "-2@todotxt", local + "-3@todotxt"}
+	if !reflect.DeepEqual(uids, expected) {
+		t.Errorf("ICalUIDs() = %v, expected %v", uids, expected)
+	}
+}
+
+func TestWriteICal(t *testing.T) {
+	todos := parseTestTodos(t,
+		"(A) 2025-01-08 Call Mom, then Dad +Family @phone due:2025-01-15 t:2025-01-10",
+		"x 2025-01-09 2025-01-02 Buy milk @store",
+	)
+
+	var buf bytes.Buffer
+	now := time.Date(2025, 1, 10, 9, 30, 0, 0, time.UTC)
+	if err := WriteICal(&buf, todos, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:" + ICalProductID + "\r\n",
+		"SUMMARY:Call Mom\\, then Dad\r\n",
+		"DTSTAMP:20250110T093000Z\r\n",
+		"CREATED:20250108T000000Z\r\n",
+		"DTSTART;VALUE=DATE:20250110\r\n",
+		"DUE;VALUE=DATE:20250115\r\n",
+		"PRIORITY:1\r\n",
+		"CATEGORIES:+Family,@phone\r\n",
+		"STATUS:NEEDS-ACTION\r\n",
+		"STATUS:COMPLETED\r\nCOMPLETED:20250109T000000Z\r\n",
+		"UID:" + ICalUID(todos[1]) + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Count(out, "BEGIN:VTODO") != 2 {
+		t.Errorf("Expected 2 VTODOs:\n%s", out)
+	}
+}
+
+func TestWriteICalStartAndDue(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{line: "Pay rent t:2025-01-10 due:2025-01-15", expected: []string{"DTSTART;VALUE=DATE:20250110", "DUE;VALUE=DATE:20250115"}},
+		{line: "Pay rent t:2025-01-15 due:2025-01-15", expected: []string{"DUE;VALUE=DATE:20250115"}},
+		{line: "Pay rent t:2025-01-20 due:2025-01-15", expected: []string{"DUE;VALUE=DATE:20250115"}},
+		{line: "Pay rent t:2025-01-15", expected: []string{"DTSTART;VALUE=DATE:20250115"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteICal(&buf, parseTestTodos(t, tt.line), time.Now()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\r\n") {
+				if strings.HasPrefix(line, "DTSTART") || strings.HasPrefix(line, "DUE") {
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteICalFolding(t *testing.T) {
+	todo, _ := ParseTodo(strings.Repeat("é", 100))
+
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, []*Todo{todo}, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Fold split a UTF-8 sequence: %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat("é", 100)+"\r\n") {
+		t.Errorf("Unfolded output lost the summary:\n%s", unfolded)
+	}
+}
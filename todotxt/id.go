@@ -0,0 +1,81 @@
+package todotxt
+
+import (
+	"crypto/rand"
+	"strconv"
+)
+
+// IDTag is the tag key holding a task's stable identifier. Unlike the
+// line-number ID, it stays with the task across deletes and archives.
+const IDTag = "id"
+
+// stableIDAlphabet leaves out characters that are easily confused, and
+// stable IDs always start with a letter so they never look like a line
+// number.
+const (
+	stableIDLetters  = "abcdefghjkmnpqrstuvwxyz"
+	stableIDAlphabet = stableIDLetters + "23456789"
+	stableIDLength   = 6
+)
+
+// newStableID is NewStableID, replaced in tests to force collisions.
+var newStableID = NewStableID
+
+func NewStableID() string {
+	b := make([]byte, stableIDLength)
+	rand.Read(b)
+	id := make([]byte, stableIDLength)
+	id[0] = stableIDLetters[int(b[0])%len(stableIDLetters)]
+	for i := 1; i < stableIDLength; i++ {
+		id[i] = stableIDAlphabet[int(b[i])%len(stableIDAlphabet)]
+	}
+	return string(id)
+}
+
+func (t *Todo) StableID() string {
+	return t.Tags[IDTag]
+}
+
+// AssignStableID gives todo an id: tag that is unique within the file and
+// others, such as its done.txt, unless it already has one, and returns it.
+func (tf *TodoFile) AssignStableID(todo *Todo, others ...*TodoFile) string {
+	if id := todo.StableID(); id != "" {
+		return id
+	}
+
+	taken := func(id string) bool {
+		for _, file := range append([]*TodoFile{tf}, others...) {
+			if file.GetByStableID(id) != nil {
+				return true
+			}
+		}
+		return false
+	}
+	id := newStableID()
+	for taken(id) {
+		id = newStableID()
+	}
+
+	if todo.Tags == nil {
+		todo.Tags = make(map[string]string)
+	}
+	todo.AddTag(IDTag, id)
+	return id
+}
+
+func (tf *TodoFile) GetByStableID(id string) *Todo {
+	for _, todo := range tf.Todos {
+		if todo.StableID() == id {
+			return todo
+		}
+	}
+	return nil
+}
+
+// Lookup finds a task by line number or by stable ID.
+func (tf *TodoFile) Lookup(ref string) *Todo {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return tf.GetByID(id)
+	}
+	return tf.GetByStableID(ref)
+}
@@ -0,0 +1,85 @@
+package todotxt
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNewStableID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewStableID()
+		if len(id) != stableIDLength {
+			t.Fatalf("Expected ID of length %d, got %q", stableIDLength, id)
+		}
+		if _, err := strconv.Atoi(id); err == nil {
+			t.Fatalf("ID should never look like a line number, got %q", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) < 95 {
+		t.Errorf("IDs should be random, got %d distinct out of 100", len(seen))
+	}
+}
+
+func TestTodoFileStableIDs(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+
+	todo1 := NewTodo("Task 1")
+	tf.Add(todo1)
+	id1 := tf.AssignStableID(todo1)
+
+	todo2, _ := ParseTodo("Task 2 id:abc123")
+	tf.Add(todo2)
+	if id := tf.AssignStableID(todo2); id != "abc123" {
+		t.Errorf("Existing ID should be kept, got %q", id)
+	}
+
+	todo3 := NewTodo("Task 3")
+	tf.Add(todo3)
+
+	if got := tf.Lookup(id1); got != todo1 {
+		t.Error("Should find task by stable ID")
+	}
+
+	if got := tf.Lookup("2"); got != todo2 {
+		t.Error("Should find task by line number")
+	}
+
+	tf.Delete(1)
+
+	if got := tf.Lookup("abc123"); got != todo2 {
+		t.Error("Stable ID should survive deletes")
+	}
+
+	if got := tf.Lookup("1"); got != todo2 {
+		t.Error("Line numbers should shift after delete")
+	}
+
+	if tf.Lookup("zzzzzz") != nil || tf.Lookup("99") != nil {
+		t.Error("Should return nil for unknown references")
+	}
+}
+
+func TestAssignStableIDAvoidsOtherFiles(t *testing.T) {
+	ids := []string{"aaaaaa", "bbbbbb", "cccccc"}
+	defer func(saved func() string) { newStableID = saved }(newStableID)
+	newStableID = func() string {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	}
+
+	open, _ := ParseTodo("Open task id:bbbbbb")
+	archived, _ := ParseTodo("x Archived task id:aaaaaa")
+	tf := NewTodoFile("todo.txt")
+	tf.Add(open)
+	done := NewTodoFile("done.txt")
+	done.Add(archived)
+
+	todo := NewTodo("New task")
+	tf.Add(todo)
+	if id := tf.AssignStableID(todo, done); id != "cccccc" {
+		t.Errorf("AssignStableID() = %q, want an id used in neither file", id)
+	}
+}
@@ -0,0 +1,395 @@
+package todotxt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RowError reports an input record that could not be turned into a task.
+// Row is the 1-based position of the object in JSON and NDJSON input and
+// the line number in CSV and TSV input.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ReadRecords reads tasks written in the Record schema, as produced by
+// WriteRecords. Every row is validated, including the checks of Lint:
+// valid rows are returned as todos without IDs, ready for TodoFile.Add,
+// and invalid rows as RowErrors. The error is only set when the input as
+// a whole cannot be read. Relative due: and t: dates are resolved with the
+// package clock.
+//
+// Fields and columns outside the schema become tags. The id field is
+// ignored, since tasks get new line numbers when they are added.
+func ReadRecords(r io.Reader, format string) ([]*Todo, []*RowError, error) {
+	switch format {
+	case FormatJSON, FormatNDJSON:
+		return readJSONRecords(r)
+	case FormatCSV, FormatTSV:
+		return readTableRecords(r, format)
+	}
+	return nil, nil, fmt.Errorf("invalid format: %s (must be json, ndjson, csv or tsv)", format)
+}
+
+// recordTodo builds the task of a record read by ReadRecords. Relative
+// due: and t: dates are resolved like add resolves them, and a task that
+// Lint would report is rejected, so imports only ever add clean lines.
+func recordTodo(r Record) (*Todo, error) {
+	todo, err := r.Todo()
+	if err != nil {
+		return nil, err
+	}
+	if err := todo.ResolveDates(Now()); err != nil {
+		return nil, err
+	}
+	if diagnostics := LintLine(todo.String(), 1); len(diagnostics) > 0 {
+		d := diagnostics[0]
+		return nil, fmt.Errorf("%s [%s]", d.Message, d.Rule)
+	}
+	return todo, nil
+}
+
+// Todo builds the task a record describes. When Text is set the task is
+// parsed from it and the other fields are ignored, so records written by
+// WriteRecords read back unchanged.
+func (r Record) Todo() (*Todo, error) {
+	if strings.TrimSpace(r.Text) != "" {
+		if strings.ContainsAny(r.Text, "\r\n") {
+			return nil, errors.New("text: must be a single line")
+		}
+		return ParseTodo(r.Text)
+	}
+
+	description := strings.Join(strings.Fields(r.Description), " ")
+	if description == "" {
+		return nil, errors.New("description: a description or text is required")
+	}
+
+	var parts []string
+	var priority Priority = PriorityNone
+	if r.Priority != "" {
+		p, ok := parsePriority("(" + r.Priority + ")")
+		if !ok {
+			return nil, fmt.Errorf("priority: %q is not a letter A-Z", r.Priority)
+		}
+		if r.Complete {
+			return nil, errors.New("priority: completed tasks cannot have a priority")
+		}
+		priority = p
+		parts = append(parts, "("+r.Priority+")")
+	}
+
+	if r.Complete {
+		parts = append([]string{"x"}, parts...)
+		if r.CompletionDate != "" {
+			if _, ok := parseDate(r.CompletionDate); !ok {
+				return nil, fmt.Errorf("completion_date: %q is not a YYYY-MM-DD date", r.CompletionDate)
+			}
+			parts = append(parts, r.CompletionDate)
+		} else if r.CreationDate != "" {
+			return nil, errors.New("creation_date: completed tasks need a completion_date to keep a creation_date")
+		}
+	} else if r.CompletionDate != "" {
+		return nil, errors.New("completion_date: only completed tasks can have a completion date")
+	}
+
+	if r.CreationDate != "" {
+		if _, ok := parseDate(r.CreationDate); !ok {
+			return nil, fmt.Errorf("creation_date: %q is not a YYYY-MM-DD date", r.CreationDate)
+		}
+		parts = append(parts, r.CreationDate)
+	}
+
+	parts = append(parts, description)
+
+	for _, project := range r.Projects {
+		name := strings.TrimPrefix(project, "+")
+		if name == "" || strings.ContainsFunc(name, isSpaceRune) {
+			return nil, fmt.Errorf("projects: %q is not a valid project name", project)
+		}
+		parts = append(parts, "+"+name)
+	}
+	for _, context := range r.Contexts {
+		name := strings.TrimPrefix(context, "@")
+		if name == "" || strings.ContainsFunc(name, isSpaceRune) {
+			return nil, fmt.Errorf("contexts: %q is not a valid context name", context)
+		}
+		parts = append(parts, "@"+name)
+	}
+
+	keys := make([]string, 0, len(r.Tags))
+	for key := range r.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tag := key + ":" + r.Tags[key]
+		if _, _, ok := splitTag(tag); !ok || strings.ContainsFunc(tag, isSpaceRune) {
+			return nil, fmt.Errorf("tags: %q is not a valid key:value tag", tag)
+		}
+		parts = append(parts, tag)
+	}
+
+	todo, err := ParseTodo(strings.Join(parts, " "))
+	if err != nil {
+		return nil, err
+	}
+	if todo.Complete != r.Complete || todo.Priority != priority || (todo.CreationDate == nil) != (r.CreationDate == "") {
+		return nil, errors.New("description: would be read as part of the task's completion mark, priority or dates")
+	}
+	return todo, nil
+}
+
+func isSpaceRune(r rune) bool {
+	return r < 0x80 && isSpace(byte(r))
+}
+
+func readJSONRecords(r io.Reader) ([]*Todo, []*RowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	var rows []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &rows); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var row json.RawMessage
+			err := decoder.Decode(&row)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse JSON at object %d: %w", len(rows)+1, err)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	var todos []*Todo
+	var rowErrors []*RowError
+	for i, row := range rows {
+		todo, err := jsonRecord(row)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Row: i + 1, Err: err})
+			continue
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rowErrors, nil
+}
+
+func jsonRecord(row json.RawMessage) (*Todo, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(row, &fields); err != nil || fields == nil {
+		return nil, errors.New("not a JSON object")
+	}
+
+	r := Record{Tags: make(map[string]string)}
+	for name, raw := range fields {
+		var err error
+		switch name {
+		case "id":
+		case "complete":
+			err = json.Unmarshal(raw, &r.Complete)
+		case "priority":
+			err = json.Unmarshal(raw, &r.Priority)
+		case "creation_date":
+			err = json.Unmarshal(raw, &r.CreationDate)
+		case "completion_date":
+			err = json.Unmarshal(raw, &r.CompletionDate)
+		case "description":
+			err = json.Unmarshal(raw, &r.Description)
+		case "text":
+			err = json.Unmarshal(raw, &r.Text)
+		case "projects":
+			r.Projects, err = jsonNames(raw)
+		case "contexts":
+			r.Contexts, err = jsonNames(raw)
+		case "tags":
+			var tags map[string]json.RawMessage
+			if err = json.Unmarshal(raw, &tags); err == nil {
+				for key, value := range tags {
+					if err = jsonTag(r.Tags, key, value); err != nil {
+						break
+					}
+				}
+			}
+		default:
+			err = jsonTag(r.Tags, name, raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, jsonTypeError(err))
+		}
+	}
+	return recordTodo(r)
+}
+
+// jsonNames accepts a list of names or a single space-separated string.
+func jsonNames(raw json.RawMessage) ([]string, error) {
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil {
+		return names, nil
+	}
+	var joined string
+	if err := json.Unmarshal(raw, &joined); err != nil {
+		return nil, errors.New("must be a list of strings")
+	}
+	return strings.Fields(joined), nil
+}
+
+// jsonTag adds a tag from a JSON string, number or boolean. Null skips it.
+func jsonTag(tags map[string]string, key string, raw json.RawMessage) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+	case string:
+		tags[key] = v
+	case json.Number:
+		tags[key] = v.String()
+	case bool:
+		tags[key] = strconv.FormatBool(v)
+	default:
+		return errors.New("tag values must be strings, numbers or booleans")
+	}
+	return nil
+}
+
+func jsonTypeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("must be a %s, not a JSON %s", typeErr.Type, typeErr.Value)
+	}
+	return err
+}
+
+func readTableRecords(r io.Reader, format string) ([]*Todo, []*RowError, error) {
+	reader := csv.NewReader(r)
+	if format == FormatTSV {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s header: %w", format, err)
+	}
+
+	known := make(map[string]bool, len(RecordColumns))
+	for _, column := range RecordColumns {
+		known[column] = true
+	}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !known[column] && !isTagKey(column) {
+			return nil, nil, fmt.Errorf("column %d: %q is neither a record field nor a tag key", i+1, header[i])
+		}
+		header[i] = column
+	}
+
+	var todos []*Todo
+	var rowErrors []*RowError
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", format, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(fields) != len(header) {
+			rowErrors = append(rowErrors, &RowError{
+				Row: line,
+				Err: fmt.Errorf("expected %d fields, got %d", len(header), len(fields)),
+			})
+			continue
+		}
+
+		todo, err := tableRecord(header, fields)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Row: line, Err: err})
+			continue
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rowErrors, nil
+}
+
+func tableRecord(header, fields []string) (*Todo, error) {
+	r := Record{Tags: make(map[string]string)}
+	for i, column := range header {
+		value := strings.TrimSpace(fields[i])
+		switch column {
+		case "id":
+		case "complete":
+			if value == "" {
+				continue
+			}
+			complete, err := strconv.ParseBool(value)
+			if value == "x" {
+				complete, err = true, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("complete: %q is not true or false", value)
+			}
+			r.Complete = complete
+		case "priority":
+			r.Priority = value
+		case "creation_date":
+			r.CreationDate = value
+		case "completion_date":
+			r.CompletionDate = value
+		case "description":
+			r.Description = value
+		case "text":
+			r.Text = value
+		case "projects":
+			r.Projects = strings.Fields(value)
+		case "contexts":
+			r.Contexts = strings.Fields(value)
+		case "tags":
+			for _, tag := range strings.Fields(value) {
+				key, value, ok := splitTag(tag)
+				if !ok {
+					return nil, fmt.Errorf("tags: %q is not a valid key:value tag", tag)
+				}
+				r.Tags[key] = value
+			}
+		default:
+			if value != "" {
+				r.Tags[column] = value
+			}
+		}
+	}
+	return recordTodo(r)
+}
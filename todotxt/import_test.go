@@ -0,0 +1,261 @@
+package todotxt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordTodo(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   Record
+		expected string
+		err      string
+	}{
+		{
+			name: "all fields",
+			record: Record{
+				Priority:     "A",
+				CreationDate: "2025-01-08",
+				Description:  "Call Mom",
+				Projects:     []string{"Family"},
+				Contexts:     []string{"@phone"},
+				Tags:         map[string]string{"due": "2025-01-15", "est": "30m"},
+			},
+			expected: "(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 est:30m",
+		},
+		{
+			name: "completed",
+			record: Record{
+				Complete:       true,
+				CompletionDate: "2025-01-09",
+				CreationDate:   "2025-01-08",
+				Description:    "Buy milk",
+			},
+			expected: "x 2025-01-09 2025-01-08 Buy milk",
+		},
+		{
+			name:     "text wins",
+			record:   Record{Text: "(B) Pay rent +Home", Description: "ignored", Priority: "Q"},
+			expected: "(B) Pay rent +Home",
+		},
+		{
+			name:   "missing description",
+			record: Record{Priority: "A"},
+			err:    "description:",
+		},
+		{
+			name:   "bad priority",
+			record: Record{Priority: "AA", Description: "Task"},
+			err:    "priority:",
+		},
+		{
+			name:   "bad date",
+			record: Record{CreationDate: "2025-1-8", Description: "Task"},
+			err:    "creation_date:",
+		},
+		{
+			name:   "completion date on open task",
+			record: Record{CompletionDate: "2025-01-08", Description: "Task"},
+			err:    "completion_date:",
+		},
+		{
+			name:   "priority on completed task",
+			record: Record{Complete: true, Priority: "A", Description: "Task"},
+			err:    "priority:",
+		},
+		{
+			name:   "project with space",
+			record: Record{Description: "Task", Projects: []string{"Big Project"}},
+			err:    "projects:",
+		},
+		{
+			name:   "bad tag key",
+			record: Record{Description: "Task", Tags: map[string]string{"1st": "x"}},
+			err:    "tags:",
+		},
+		{
+			name:   "description looks like a header",
+			record: Record{Description: "x marks the spot"},
+			err:    "description:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo, err := tt.record.Todo()
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("Expected error starting with %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if todo.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, todo.String())
+			}
+		})
+	}
+}
+
+func TestReadRecordsJSON(t *testing.T) {
+	input := `[
+  {"priority": "A", "description": "Plan", "projects": ["Work"], "tags": {"due": "2025-01-20"}},
+  {"description": "Estimate", "projects": "Work Home", "est": 3, "owner": "kim"},
+  {"description": "Bad", "priority": 1},
+  "not an object"
+]`
+	todos, rowErrors, err := ReadRecords(strings.NewReader(input), FormatJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"(A) Plan +Work due:2025-01-20",
+		"Estimate +Work +Home est:3 owner:kim",
+	}
+	if len(todos) != len(expected) {
+		t.Fatalf("Expected %d todos, got %d", len(expected), len(todos))
+	}
+	for i, want := range expected {
+		if todos[i].String() != want {
+			t.Errorf("Todo %d: expected %q, got %q", i, want, todos[i].String())
+		}
+	}
+
+	if len(rowErrors) != 2 {
+		t.Fatalf("Expected 2 row errors, got %v", rowErrors)
+	}
+	if rowErrors[0].Row != 3 || !strings.Contains(rowErrors[0].Error(), "priority") {
+		t.Errorf("Unexpected first row error: %v", rowErrors[0])
+	}
+	if rowErrors[1].Row != 4 {
+		t.Errorf("Expected error on row 4, got %v", rowErrors[1])
+	}
+}
+
+func TestReadRecordsNDJSON(t *testing.T) {
+	input := "{\"description\": \"One\"}\n{\"description\": \"Two\", \"complete\": true}\n"
+	todos, rowErrors, err := ReadRecords(strings.NewReader(input), FormatNDJSON)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("Unexpected errors: %v %v", err, rowErrors)
+	}
+	if len(todos) != 2 || todos[1].String() != "x Two" {
+		t.Errorf("Unexpected todos: %v", todos)
+	}
+
+	_, _, err = ReadRecords(strings.NewReader("{\"description\": "), FormatNDJSON)
+	if err == nil {
+		t.Error("Expected an error for truncated JSON")
+	}
+}
+
+func TestReadRecordsCSV(t *testing.T) {
+	input := "Description,Priority,Projects,Contexts,complete,sprint\n" +
+		"Plan,A,Work,office,,3\n" +
+		"Archive,,Work,,true,\n" +
+		"Broken,ZZ,,,,\n" +
+		"Short,B\n"
+	todos, rowErrors, err := ReadRecords(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(todos) != 2 {
+		t.Fatalf("Expected 2 todos, got %d", len(todos))
+	}
+	if todos[0].String() != "(A) Plan +Work @office sprint:3" {
+		t.Errorf("Unexpected first todo: %q", todos[0].String())
+	}
+	if todos[1].String() != "x Archive +Work" {
+		t.Errorf("Unexpected second todo: %q", todos[1].String())
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Row != 4 || rowErrors[1].Row != 5 {
+		t.Errorf("Expected errors on lines 4 and 5, got %v", rowErrors)
+	}
+}
+
+func TestReadRecordsLintsAndResolvesDates(t *testing.T) {
+	defer SetClock(SetClock(FixedClock(time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)))) // Wednesday
+
+	input := `{"text": "Pay rent due:fri"}
+{"description": "Plan trip", "tags": {"t": "+1w"}}
+{"text": "Bad date due:2025-13-45"}
+{"description": "Stretch", "tags": {"rec": "zz"}}
+{"text": "(a) Lowercase priority"}
+{"text": "Someday due:someday"}
+`
+	todos, rowErrors, err := ReadRecords(strings.NewReader(input), FormatNDJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"Pay rent due:2025-01-10", "Plan trip t:2025-01-15"}
+	if len(todos) != len(expected) {
+		t.Fatalf("Expected %d todos, got %d", len(expected), len(todos))
+	}
+	for i, want := range expected {
+		if todos[i].String() != want {
+			t.Errorf("Todo %d: expected %q, got %q", i, want, todos[i].String())
+		}
+	}
+
+	wantErrors := []struct {
+		row  int
+		text string
+	}{
+		{3, "due: invalid date"},
+		{4, RuleInvalidRecurrence},
+		{5, RuleLowercasePriority},
+		{6, "due: invalid date"},
+	}
+	if len(rowErrors) != len(wantErrors) {
+		t.Fatalf("Expected %d row errors, got %v", len(wantErrors), rowErrors)
+	}
+	for i, want := range wantErrors {
+		if rowErrors[i].Row != want.row || !strings.Contains(rowErrors[i].Error(), want.text) {
+			t.Errorf("Row error %d: expected row %d with %q, got %v", i, want.row, want.text, rowErrors[i])
+		}
+	}
+}
+
+func TestReadRecordsCSVBadHeader(t *testing.T) {
+	_, _, err := ReadRecords(strings.NewReader("description,due date\nTask,2025-01-01\n"), FormatCSV)
+	if err == nil || !strings.Contains(err.Error(), "due date") {
+		t.Errorf("Expected a header error, got %v", err)
+	}
+}
+
+func TestReadRecordsRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatNDJSON, FormatCSV, FormatTSV} {
+		t.Run(format, func(t *testing.T) {
+			original := parseTestTodos(t,
+				"(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa",
+				"x 2025-01-09 Buy milk, eggs @store",
+			)
+
+			var buf bytes.Buffer
+			if err := WriteRecords(&buf, format, original); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			todos, rowErrors, err := ReadRecords(&buf, format)
+			if err != nil || len(rowErrors) != 0 {
+				t.Fatalf("Unexpected errors: %v %v", err, rowErrors)
+			}
+			if len(todos) != len(original) {
+				t.Fatalf("Expected %d todos, got %d", len(original), len(todos))
+			}
+			for i := range original {
+				if todos[i].String() != original[i].String() {
+					t.Errorf("Expected %q, got %q", original[i].String(), todos[i].String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package todotxt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// DefaultJournalLimit is the number of operations a journal keeps when
+// Journal.Limit is zero.
+const DefaultJournalLimit = 100
+
+// FileChange records how one file changed in an operation as a line diff:
+// from line Line on, the lines Before were replaced with After. Lines keep
+// their line endings. The hashes identify the whole file on either side,
+// so that a file edited since is detected; an empty hash means the file
+// did not exist.
+type FileChange struct {
+	Path       string   `json:"path"`
+	Line       int      `json:"line"`
+	Before     []string `json:"before,omitempty"`
+	After      []string `json:"after,omitempty"`
+	BeforeHash string   `json:"before_hash,omitempty"`
+	AfterHash  string   `json:"after_hash,omitempty"`
+}
+
+// NewFileChange returns the change of the file at path from before to
+// after, in the form ReadFileContent returns. The diff covers the lines
+// between the common beginning and end of both versions.
+func NewFileChange(path string, before, after *string) FileChange {
+	change := FileChange{Path: path, BeforeHash: contentHash(before), AfterHash: contentHash(after)}
+	from, to := splitLines(before), splitLines(after)
+
+	for change.Line < len(from) && change.Line < len(to) && from[change.Line] == to[change.Line] {
+		change.Line++
+	}
+	fromEnd, toEnd := len(from), len(to)
+	for fromEnd > change.Line && toEnd > change.Line && from[fromEnd-1] == to[toEnd-1] {
+		fromEnd--
+		toEnd--
+	}
+	change.Before = from[change.Line:fromEnd]
+	change.After = to[change.Line:toEnd]
+	return change
+}
+
+// Operation is one reversible step in a journal, usually one command.
+type Operation struct {
+	Time    time.Time    `json:"time"`
+	Command string       `json:"command"`
+	Changes []FileChange `json:"changes"`
+}
+
+// Journal is an undo/redo history of file changes stored at Path.
+// Operations before the cursor have been applied and can be undone; those
+// after it have been undone and can be redone.
+//
+// The file holds one JSON entry per line: a recorded operation, an undo or
+// a redo. Entries are appended, and the file is rewritten with only the
+// operations still kept once it has grown to twice the limit.
+//
+// A journal does not lock the files it restores; callers should hold the
+// locks of every file an operation touches.
+type Journal struct {
+	Path  string
+	Limit int
+}
+
+type journalData struct {
+	Operations []Operation
+	Cursor     int
+	entries    int
+}
+
+// journalEntry is one line of a journal file. Exactly one field is set.
+type journalEntry struct {
+	Operation *Operation `json:"operation,omitempty"`
+	Undo      bool       `json:"undo,omitempty"`
+	Redo      bool       `json:"redo,omitempty"`
+}
+
+func NewJournal(path string) *Journal {
+	return &Journal{Path: path}
+}
+
+func (j *Journal) limit() int {
+	if j.Limit <= 0 {
+		return DefaultJournalLimit
+	}
+	return j.Limit
+}
+
+// Record appends op after the cursor, discarding any operations that could
+// have been redone, and drops the oldest operations beyond the limit.
+func (j *Journal) Record(op Operation) error {
+	data, err := j.load()
+	if err != nil {
+		return err
+	}
+
+	data.Operations = append(data.Operations[:data.Cursor], op)
+	data.Cursor = len(data.Operations)
+	j.trim(data)
+
+	return j.write(data, journalEntry{Operation: &op})
+}
+
+// Undo restores the files changed by the most recent applied operation and
+// returns it. The files must still hold what the operation wrote;
+// otherwise nothing is restored and the error wraps ErrConflict.
+func (j *Journal) Undo() (*Operation, error) {
+	data, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	if data.Cursor == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	op := data.Operations[data.Cursor-1]
+	if err := applyChanges(op.Changes, true); err != nil {
+		return nil, err
+	}
+
+	data.Cursor--
+	return &op, j.write(data, journalEntry{Undo: true})
+}
+
+// Redo re-applies the most recently undone operation and returns it.
+func (j *Journal) Redo() (*Operation, error) {
+	data, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	if data.Cursor == len(data.Operations) {
+		return nil, ErrNothingToRedo
+	}
+
+	op := data.Operations[data.Cursor]
+	if err := applyChanges(op.Changes, false); err != nil {
+		return nil, err
+	}
+
+	data.Cursor++
+	return &op, j.write(data, journalEntry{Redo: true})
+}
+
+// History returns the recorded operations, oldest first, and the cursor:
+// the number of operations currently applied.
+func (j *Journal) History() ([]Operation, int, error) {
+	data, err := j.load()
+	if err != nil {
+		return nil, 0, err
+	}
+	return data.Operations, data.Cursor, nil
+}
+
+// applyChanges moves every file from one side of its change to the other,
+// after checking that all of them are still on the expected side.
+func applyChanges(changes []FileChange, undo bool) error {
+	contents := make([]*string, len(changes))
+	for i, change := range changes {
+		expected := change.BeforeHash
+		if undo {
+			expected = change.AfterHash
+		}
+		current, err := ReadFileContent(change.Path)
+		if err != nil {
+			return err
+		}
+		if contentHash(current) != expected {
+			return fmt.Errorf("%s: %w", change.Path, ErrConflict)
+		}
+		contents[i] = current
+	}
+
+	for i, change := range changes {
+		from, to, target := change.Before, change.After, change.AfterHash
+		if undo {
+			from, to, target = change.After, change.Before, change.BeforeHash
+		}
+		if target == "" {
+			if err := os.Remove(change.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove file: %w", err)
+			}
+			continue
+		}
+
+		lines := splitLines(contents[i])
+		var b strings.Builder
+		for _, line := range lines[:change.Line] {
+			b.WriteString(line)
+		}
+		for _, line := range to {
+			b.WriteString(line)
+		}
+		for _, line := range lines[change.Line+len(from):] {
+			b.WriteString(line)
+		}
+		if err := writeFileAtomic(change.Path, []byte(b.String())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadFileContent returns the content of path, or nil if it does not
+// exist, in the form NewFileChange takes.
+func ReadFileContent(path string) (*string, error) {
+	data, state, err := readFile(path)
+	if err != nil || !state.exists {
+		return nil, err
+	}
+	content := string(data)
+	return &content, nil
+}
+
+func contentHash(content *string) string {
+	if content == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(*content))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitLines splits content after every newline, so that joining the lines
+// gives it back.
+func splitLines(content *string) []string {
+	if content == nil || *content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(*content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// trim drops the oldest operations beyond the limit.
+func (j *Journal) trim(data *journalData) {
+	if excess := len(data.Operations) - j.limit(); excess > 0 {
+		data.Operations = data.Operations[excess:]
+		data.Cursor = max(data.Cursor-excess, 0)
+	}
+}
+
+func (j *Journal) load() (*journalData, error) {
+	data := &journalData{}
+	raw, err := os.ReadFile(j.Path)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, len(raw)+1)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", lineNo, err)
+		}
+		data.entries++
+		switch {
+		case entry.Operation != nil:
+			data.Operations = append(data.Operations[:data.Cursor], *entry.Operation)
+			data.Cursor = len(data.Operations)
+		case entry.Undo && data.Cursor > 0:
+			data.Cursor--
+		case entry.Redo && data.Cursor < len(data.Operations):
+			data.Cursor++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	j.trim(data)
+	return data, nil
+}
+
+// write appends entry to the journal file, or rewrites the file from data,
+// which already includes entry, once it holds twice the limit of entries.
+func (j *Journal) write(data *journalData, entry journalEntry) error {
+	if data.entries+1 < 2*j.limit() {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal: %w", err)
+		}
+		file, err := os.OpenFile(j.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write journal: %w", err)
+		}
+		return file.Close()
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	entries := make([]journalEntry, 0, len(data.Operations))
+	for i := range data.Operations {
+		entries = append(entries, journalEntry{Operation: &data.Operations[i]})
+	}
+	for i := data.Cursor; i < len(data.Operations); i++ {
+		entries = append(entries, journalEntry{Undo: true})
+	}
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode journal: %w", err)
+		}
+	}
+	return writeFileAtomic(j.Path, buf.Bytes())
+}
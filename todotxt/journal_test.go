@@ -0,0 +1,234 @@
+package todotxt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordChange(t *testing.T, j *Journal, command string, paths []string, change func()) {
+	t.Helper()
+	var changes []FileChange
+	befores := make([]*string, len(paths))
+	for i, path := range paths {
+		befores[i], _ = ReadFileContent(path)
+	}
+	change()
+	for i, path := range paths {
+		after, _ := ReadFileContent(path)
+		changes = append(changes, NewFileChange(path, befores[i], after))
+	}
+	if err := j.Record(Operation{Time: time.Now(), Command: command, Changes: changes}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "<missing>"
+	}
+	return string(data)
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	dir := t.TempDir()
+	todoPath := filepath.Join(dir, "todo.txt")
+	donePath := filepath.Join(dir, "done.txt")
+	j := NewJournal(filepath.Join(dir, "todo.txt.journal"))
+
+	recordChange(t, j, "add Task 1", []string{todoPath, donePath}, func() {
+		os.WriteFile(todoPath, []byte("Task 1\n"), 0644)
+	})
+	recordChange(t, j, "archive", []string{todoPath, donePath}, func() {
+		os.WriteFile(todoPath, []byte(""), 0644)
+		os.WriteFile(donePath, []byte("Task 1\n"), 0644)
+	})
+
+	op, err := j.Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if op.Command != "archive" {
+		t.Errorf("Expected to undo archive, got %q", op.Command)
+	}
+	if readString(t, todoPath) != "Task 1\n" || readString(t, donePath) != "<missing>" {
+		t.Error("Undo should restore both files")
+	}
+
+	if _, err := j.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if readString(t, todoPath) != "<missing>" {
+		t.Error("Undoing the first add should remove the created file")
+	}
+
+	if _, err := j.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+
+	op, err = j.Redo()
+	if err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if op.Command != "add Task 1" || readString(t, todoPath) != "Task 1\n" {
+		t.Error("Redo should re-apply add")
+	}
+
+	ops, cursor, err := j.History()
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(ops) != 2 || cursor != 1 {
+		t.Errorf("Expected 2 operations with cursor 1, got %d and %d", len(ops), cursor)
+	}
+
+	recordChange(t, j, "add Task 2", []string{todoPath}, func() {
+		os.WriteFile(todoPath, []byte("Task 1\nTask 2\n"), 0644)
+	})
+
+	if _, err := j.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Recording should discard redo history, got %v", err)
+	}
+}
+
+func TestJournalUndoConflict(t *testing.T) {
+	dir := t.TempDir()
+	todoPath := filepath.Join(dir, "todo.txt")
+	j := NewJournal(filepath.Join(dir, "todo.txt.journal"))
+
+	recordChange(t, j, "add Task 1", []string{todoPath}, func() {
+		os.WriteFile(todoPath, []byte("Task 1\n"), 0644)
+	})
+
+	os.WriteFile(todoPath, []byte("Task 1\nEdited elsewhere\n"), 0644)
+
+	if _, err := j.Undo(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if readString(t, todoPath) != "Task 1\nEdited elsewhere\n" {
+		t.Error("A conflicting undo should not touch the file")
+	}
+}
+
+func TestJournalLimit(t *testing.T) {
+	dir := t.TempDir()
+	todoPath := filepath.Join(dir, "todo.txt")
+	j := &Journal{Path: filepath.Join(dir, "journal"), Limit: 2}
+
+	for _, content := range []string{"a\n", "b\n", "c\n"} {
+		recordChange(t, j, "edit", []string{todoPath}, func() {
+			os.WriteFile(todoPath, []byte(content), 0644)
+		})
+	}
+
+	ops, cursor, _ := j.History()
+	if len(ops) != 2 || cursor != 2 {
+		t.Errorf("Expected journal trimmed to 2 operations, got %d (cursor %d)", len(ops), cursor)
+	}
+}
+
+func TestNewFileChange(t *testing.T) {
+	content := func(s string) *string { return &s }
+
+	tests := []struct {
+		name           string
+		before, after  *string
+		expectedLine   int
+		expectedBefore []string
+		expectedAfter  []string
+	}{
+		{name: "Created", after: content("a\n"), expectedAfter: []string{"a\n"}},
+		{name: "Removed", before: content("a\nb"), expectedBefore: []string{"a\n", "b"}},
+		{name: "Appended", before: content("a\nb\n"), after: content("a\nb\nc\n"), expectedLine: 2, expectedAfter: []string{"c\n"}},
+		{name: "Edited", before: content("a\nb\nc\n"), after: content("a\nB\nc\n"), expectedLine: 1, expectedBefore: []string{"b\n"}, expectedAfter: []string{"B\n"}},
+		{name: "Deleted", before: content("a\nb\nc\n"), after: content("a\nc\n"), expectedLine: 1, expectedBefore: []string{"b\n"}},
+		{name: "Final newline added", before: content("a\nb"), after: content("a\nb\n"), expectedLine: 1, expectedBefore: []string{"b"}, expectedAfter: []string{"b\n"}},
+		{name: "Unchanged", before: content("a\n"), after: content("a\n"), expectedLine: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := NewFileChange("todo.txt", tt.before, tt.after)
+			if change.Line != tt.expectedLine || !slices.Equal(change.Before, tt.expectedBefore) || !slices.Equal(change.After, tt.expectedAfter) {
+				t.Errorf("NewFileChange() = line %d, %q -> %q; want line %d, %q -> %q",
+					change.Line, change.Before, change.After, tt.expectedLine, tt.expectedBefore, tt.expectedAfter)
+			}
+			if (change.BeforeHash == "") != (tt.before == nil) || (change.AfterHash == "") != (tt.after == nil) {
+				t.Errorf("Hashes should be empty exactly for missing files, got %q and %q", change.BeforeHash, change.AfterHash)
+			}
+		})
+	}
+}
+
+func TestJournalAppendsAndCompacts(t *testing.T) {
+	dir := t.TempDir()
+	todoPath := filepath.Join(dir, "todo.txt")
+	journalPath := filepath.Join(dir, "journal")
+	j := &Journal{Path: journalPath, Limit: 3}
+
+	lines := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		lines = append(lines, fmt.Sprintf("Task %d", i))
+	}
+	os.WriteFile(todoPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	recordChange(t, j, "do 250", []string{todoPath}, func() {
+		lines[249] = "x Task 249"
+		os.WriteFile(todoPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	})
+	first := readString(t, journalPath)
+	if strings.Count(first, "\n") != 1 || strings.Count(first, "Task") != 2 {
+		t.Errorf("Expected one entry holding only the changed line, got %q", first)
+	}
+
+	recordChange(t, j, "add Task 500", []string{todoPath}, func() {
+		os.WriteFile(todoPath, []byte(strings.Join(lines, "\n")+"\nTask 500\n"), 0644)
+	})
+	if _, err := j.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if journal := readString(t, journalPath); !strings.HasPrefix(journal, first) || strings.Count(journal, "\n") != 3 {
+		t.Errorf("Expected entries appended after the first, got %q", journal)
+	}
+
+	for _, command := range []string{"edit 1", "edit 2", "edit 3"} {
+		recordChange(t, j, command, []string{todoPath}, func() {
+			lines[0] += "!"
+			os.WriteFile(todoPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+		})
+	}
+	if _, err := j.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	journal := readString(t, journalPath)
+	if n := strings.Count(journal, "\n"); n >= 2*j.Limit {
+		t.Errorf("Expected the journal to be compacted, got %d entries", n)
+	}
+	ops, cursor, err := j.History()
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	var commands []string
+	for _, op := range ops {
+		commands = append(commands, op.Command)
+	}
+	if !reflect.DeepEqual(commands, []string{"edit 1", "edit 2", "edit 3"}) || cursor != 2 {
+		t.Errorf("History() = %v with cursor %d", commands, cursor)
+	}
+
+	if _, err := j.Redo(); err != nil {
+		t.Fatalf("Redo after compaction failed: %v", err)
+	}
+	if got := strings.SplitN(readString(t, todoPath), "\n", 2)[0]; got != "Task 0!!!" {
+		t.Errorf("Expected redo to restore the third edit, got %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule IDs reported by Lint.
+const (
+	RuleInvalidDate              = "invalid-date"
+	RuleLowercasePriority        = "lowercase-priority"
+	RuleCompletedWithPriority    = "completed-with-priority"
+	RuleCompletionBeforeCreation = "completion-before-creation"
+	RuleInvalidTagDate           = "invalid-tag-date"
+	RuleInvalidRecurrence        = "invalid-recurrence"
+)
+
+// DateTags lists the tag keys whose values must be YYYY-MM-DD dates.
+var DateTags = []string{"due", "t"}
+
+// Diagnostic describes one problem in a todo.txt file. Line and Column are
+// 1-based; Column counts bytes.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s [%s]", d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+var looseDateRegex = regexp.MustCompile(`^\d{4}-\d{1,2}-\d{1,2}$`)
+
+// Lint checks every line read from r and returns the diagnostics in line
+// order. The error is only non-nil when reading fails.
+func Lint(r io.Reader) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		diagnostics = append(diagnostics, LintLine(scanner.Text(), lineNo)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return diagnostics, fmt.Errorf("failed to read: %w", err)
+	}
+	return diagnostics, nil
+}
+
+// LintLine checks a single line, reporting positions on line lineNo.
+func LintLine(line string, lineNo int) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(tok token, severity Severity, rule, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:     lineNo,
+			Column:   tok.pos + 1,
+			Severity: severity,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	words := scanWords(line)
+	if len(words) == 0 {
+		return nil
+	}
+
+	// headerDate consumes the next word if it is meant to be a header date
+	// and reports it when it is not a real one.
+	headerDate := func(what string) (tok token, present, valid bool) {
+		if len(words) == 0 || !looseDateRegex.MatchString(words[0].text) {
+			return token{}, false, false
+		}
+		tok = words[0]
+		words = words[1:]
+		if _, ok := parseDate(tok.text); !ok {
+			report(tok, SeverityError, RuleInvalidDate, "invalid %s date %q (want YYYY-MM-DD)", what, tok.text)
+			return tok, true, false
+		}
+		return tok, true, true
+	}
+
+	if words[0].text == "x" {
+		words = words[1:]
+		completionTok, completionPresent, completionOK := headerDate("completion")
+		var creationTok token
+		var creationOK bool
+		if completionPresent {
+			creationTok, _, creationOK = headerDate("creation")
+		}
+
+		if completionOK && creationOK {
+			completion, _ := parseDate(completionTok.text)
+			creation, _ := parseDate(creationTok.text)
+			if completion.Before(creation) {
+				report(completionTok, SeverityError, RuleCompletionBeforeCreation,
+					"completion date %s is before creation date %s", completionTok.text, creationTok.text)
+			}
+		}
+
+		if len(words) > 0 && isPriorityLike(words[0].text) {
+			report(words[0], SeverityWarning, RuleCompletedWithPriority,
+				"completed task still has priority %s", words[0].text)
+			words = words[1:]
+		}
+	} else {
+		if isPriorityLike(words[0].text) {
+			if _, ok := parsePriority(words[0].text); !ok {
+				report(words[0], SeverityWarning, RuleLowercasePriority,
+					"priority %s must be an uppercase letter", words[0].text)
+			}
+			words = words[1:]
+		}
+		headerDate("creation")
+	}
+
+	for _, word := range words {
+		tok := classify(word)
+		if tok.kind != tokenTag {
+			continue
+		}
+		if tok.key == RecurrenceTag {
+			if _, err := ParseRecurrence(tok.value); err != nil {
+				tok.pos += len(tok.key) + 1
+				report(tok, SeverityError, RuleInvalidRecurrence, "%v", err)
+			}
+			continue
+		}
+		if !containsString(DateTags, tok.key) {
+			continue
+		}
+		if _, ok := parseDate(tok.value); !ok {
+			tok.pos += len(tok.key) + 1
+			report(tok, SeverityError, RuleInvalidTagDate,
+				"invalid %s date %q (want YYYY-MM-DD)", tok.key, tok.value)
+		}
+	}
+
+	return diagnostics
+}
+
+func isPriorityLike(word string) bool {
+	return len(word) == 3 && word[0] == '(' && word[2] == ')' && isLetter(word[1])
+}
@@ -0,0 +1,119 @@
+package todotxt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Diagnostic
+	}{
+		{
+			name:  "Valid task",
+			input: "(A) 2025-01-08 Call Mom +Family due:2025-01-15",
+		},
+		{
+			name:  "Valid completed task",
+			input: "x 2025-01-09 2025-01-08 Write tests",
+		},
+		{
+			name:  "Invalid creation date",
+			input: "(A) 2025-13-45 Call Mom",
+			expected: []Diagnostic{
+				{Line: 1, Column: 5, Severity: SeverityError, Rule: RuleInvalidDate},
+			},
+		},
+		{
+			name:  "Invalid completion date",
+			input: "x 2025-02-30 Write tests",
+			expected: []Diagnostic{
+				{Line: 1, Column: 3, Severity: SeverityError, Rule: RuleInvalidDate},
+			},
+		},
+		{
+			name:  "Lowercase priority",
+			input: "(a) Call Mom",
+			expected: []Diagnostic{
+				{Line: 1, Column: 1, Severity: SeverityWarning, Rule: RuleLowercasePriority},
+			},
+		},
+		{
+			name:  "Completed task with priority",
+			input: "x 2025-01-09 (A) Call Mom",
+			expected: []Diagnostic{
+				{Line: 1, Column: 14, Severity: SeverityWarning, Rule: RuleCompletedWithPriority},
+			},
+		},
+		{
+			name:  "Completion before creation",
+			input: "x 2025-01-07 2025-01-08 Write tests",
+			expected: []Diagnostic{
+				{Line: 1, Column: 3, Severity: SeverityError, Rule: RuleCompletionBeforeCreation},
+			},
+		},
+		{
+			name:  "Invalid due date",
+			input: "Pay rent due:2025-13-45",
+			expected: []Diagnostic{
+				{Line: 1, Column: 14, Severity: SeverityError, Rule: RuleInvalidTagDate},
+			},
+		},
+		{
+			name:  "Invalid recurrence",
+			input: "Water plants rec:2x",
+			expected: []Diagnostic{
+				{Line: 1, Column: 18, Severity: SeverityError, Rule: RuleInvalidRecurrence},
+			},
+		},
+		{
+			name:  "Valid recurrence",
+			input: "Pay rent due:2025-02-01 rec:+1m",
+		},
+		{
+			name:  "Unknown tags are not checked",
+			input: "Pay rent est:soon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LintLine(tt.input, 1)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(tt.expected), got)
+			}
+			for i, want := range tt.expected {
+				if got[i].Line != want.Line || got[i].Column != want.Column ||
+					got[i].Severity != want.Severity || got[i].Rule != want.Rule {
+					t.Errorf("Expected %+v, got %+v", want, got[i])
+				}
+				if got[i].Message == "" {
+					t.Error("Diagnostic should have a message")
+				}
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	input := "(A) Call Mom\n\n(b) Buy milk\nPay rent due:tomorrow\n"
+
+	diagnostics, err := Lint(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+
+	if diagnostics[0].Line != 3 || diagnostics[0].Rule != RuleLowercasePriority {
+		t.Errorf("Unexpected first diagnostic: %v", diagnostics[0])
+	}
+
+	if diagnostics[1].Line != 4 || diagnostics[1].Rule != RuleInvalidTagDate {
+		t.Errorf("Unexpected second diagnostic: %v", diagnostics[1])
+	}
+}
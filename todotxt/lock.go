@@ -0,0 +1,59 @@
+package todotxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock takes an exclusive advisory lock on the todo file, blocking until it
+// is available. Hold it across the whole Load, modify, Save cycle so that
+// concurrent writers cannot lose each other's changes.
+//
+// The lock is taken on a sidecar "<Path>.lock" file rather than on Path
+// itself, because Save replaces Path with a new file.
+func (tf *TodoFile) Lock() error {
+	if tf.lock != nil {
+		return nil
+	}
+
+	// Lock next to the target of a symlink, like Save writes there.
+	path := tf.Path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to lock file: %w", err)
+	}
+
+	tf.lock = file
+	return nil
+}
+
+// Unlock releases the lock taken by Lock. It is a no-op when the file is
+// not locked.
+func (tf *TodoFile) Unlock() error {
+	if tf.lock == nil {
+		return nil
+	}
+
+	file := tf.lock
+	tf.lock = nil
+
+	if err := unlockFile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to unlock file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package todotxt
+
+import "os"
+
+// Advisory locking is only implemented with flock(2); elsewhere the lock
+// file is created but not locked.
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
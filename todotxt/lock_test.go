@@ -0,0 +1,51 @@
+//go:build unix
+
+package todotxt
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTodoFileLock(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "todo.txt")
+
+	first := NewTodoFile(testFile)
+	if err := first.Lock(); err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	second := NewTodoFile(testFile)
+	go func() {
+		if err := second.Lock(); err != nil {
+			t.Errorf("Failed to lock: %v", err)
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Second lock should block while the first is held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("Failed to unlock: %v", err)
+	}
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Second lock should be acquired after unlock")
+	}
+
+	if err := second.Unlock(); err != nil {
+		t.Fatalf("Failed to unlock: %v", err)
+	}
+
+	if err := second.Unlock(); err != nil {
+		t.Errorf("Unlocking twice should be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package todotxt
+
+import (
+	"strings"
+	"time"
+)
+
+func ParseTodo(line string) (*Todo, error) {
+	if strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+
+	todo := &Todo{
+		Projects: []string{},
+		Contexts: []string{},
+		Tags:     make(map[string]string),
+		Raw:      line,
+	}
+
+	words := scanWords(line)
+	peek := func() string {
+		if len(words) == 0 {
+			return ""
+		}
+		return words[0].text
+	}
+
+	// consume moves the next word into the header under the given role.
+	consume := func(role string) {
+		word := words[0]
+		word.key = role
+		todo.header = append(todo.header, word)
+		words = words[1:]
+	}
+
+	if peek() == "x" {
+		todo.Complete = true
+		consume(headerMark)
+
+		if date, ok := parseDate(peek()); ok {
+			todo.CompletionDate = &date
+			consume(headerCompletion)
+
+			if date, ok := parseDate(peek()); ok {
+				todo.CreationDate = &date
+				consume(headerCreation)
+			}
+		}
+	} else {
+		if p, ok := parsePriority(peek()); ok {
+			todo.Priority = p
+			consume(headerPriority)
+		}
+
+		if date, ok := parseDate(peek()); ok {
+			todo.CreationDate = &date
+			consume(headerCreation)
+		}
+	}
+
+	for _, word := range words {
+		todo.tokens = append(todo.tokens, classify(word))
+	}
+
+	for _, tok := range todo.tokens {
+		switch tok.kind {
+		case tokenProject:
+			todo.AddProject(tok.value)
+		case tokenContext:
+			todo.AddContext(tok.value)
+		case tokenTag:
+			todo.AddTag(tok.key, tok.value)
+		}
+	}
+
+	todo.Description = descriptionOf(todo.tokens)
+	todo.source = line
+	todo.rendered = todo.render()
+
+	return todo, nil
+}
+
+func parsePriority(word string) (Priority, bool) {
+	if len(word) != 3 || word[0] != '(' || word[2] != ')' {
+		return PriorityNone, false
+	}
+	if word[1] < 'A' || word[1] > 'Z' {
+		return PriorityNone, false
+	}
+	return Priority(word[1]), true
+}
+
+// parseDate accepts a strict YYYY-MM-DD date.
+func parseDate(word string) (time.Time, bool) {
+	if len(word) != len("2006-01-02") {
+		return time.Time{}, false
+	}
+	date, err := time.Parse("2006-01-02", word)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func ParseTodos(lines []string) ([]*Todo, error) {
+	var todos []*Todo
+	for i, line := range lines {
+		todo, err := ParseTodo(line)
+		if err != nil {
+			return nil, err
+		}
+		if todo != nil {
+			todo.ID = i + 1
+			todos = append(todos, todo)
+		}
+	}
+	return todos, nil
+}
@@ -1,4 +1,4 @@
-package main
+package todotxt
 
 import (
 	"testing"
@@ -178,3 +178,128 @@ func TestParseTodos(t *testing.T) {
 		t.Error("Third todo should be complete")
 	}
 }
+
+func TestParseTodoTokenizer(t *testing.T) {
+	tests := []struct {
+		input       string
+		description string
+		projects    []string
+		contexts    []string
+		tags        map[string]string
+	}{
+		{
+			input:       "Read https://example.com/docs?a=1 +Work",
+			description: "Read https://example.com/docs?a=1",
+			projects:    []string{"Work"},
+		},
+		{
+			input:       "Email bob@corp.com about lunch @office",
+			description: "Email bob@corp.com about lunch",
+			contexts:    []string{"office"},
+		},
+		{
+			input:       "Learn C++ and C# +Study",
+			description: "Learn C++ and C#",
+			projects:    []string{"Study"},
+		},
+		{
+			input:       "Standup at 10:30 tomorrow due:2025-01-15",
+			description: "Standup at 10:30 tomorrow",
+			tags:        map[string]string{"due": "2025-01-15"},
+		},
+		{
+			input:       "Check a:b:c and key: and :value",
+			description: "Check a:b:c and key: and :value",
+		},
+		{
+			input:       "Fix bug in a+b and x@y parsing",
+			description: "Fix bug in a+b and x@y parsing",
+		},
+		{
+			input:       "Lone + and @ signs stay words",
+			description: "Lone + and @ signs stay words",
+		},
+		{
+			input:       "See ftp://host/file and mailto:bob@corp.com",
+			description: "See ftp://host/file and",
+			tags:        map[string]string{"mailto": "bob@corp.com"},
+		},
+		{
+			input:       "Ratio 16:9 screen est:2h rec:+1w",
+			description: "Ratio 16:9 screen",
+			tags:        map[string]string{"est": "2h", "rec": "+1w"},
+		},
+		{
+			input:       "Tab\tseparated\t+tabs\t@here",
+			description: "Tab separated",
+			projects:    []string{"tabs"},
+			contexts:    []string{"here"},
+		},
+		{
+			input:       "x 2025-01-09 Meet due:2025-01-08 and 2025-01-07",
+			description: "Meet and 2025-01-07",
+			tags:        map[string]string{"due": "2025-01-08"},
+		},
+		{
+			input:       "(a) lowercase is not a priority",
+			description: "(a) lowercase is not a priority",
+		},
+		{
+			input:       "Unicode タスク +プロジェクト @自宅",
+			description: "Unicode タスク",
+			projects:    []string{"プロジェクト"},
+			contexts:    []string{"自宅"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			todo, err := ParseTodo(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if todo.Description != tt.description {
+				t.Errorf("Expected description '%s', got '%s'", tt.description, todo.Description)
+			}
+			if !equalStrings(todo.Projects, tt.projects) {
+				t.Errorf("Expected projects %v, got %v", tt.projects, todo.Projects)
+			}
+			if !equalStrings(todo.Contexts, tt.contexts) {
+				t.Errorf("Expected contexts %v, got %v", tt.contexts, todo.Contexts)
+			}
+			if len(todo.Tags) != len(tt.tags) {
+				t.Errorf("Expected tags %v, got %v", tt.tags, todo.Tags)
+			}
+			for key, value := range tt.tags {
+				if todo.Tags[key] != value {
+					t.Errorf("Expected tag %s:%s, got %v", key, value, todo.Tags)
+				}
+			}
+			if got := todo.String(); got != tt.input {
+				t.Errorf("Round trip changed line to '%s'", got)
+			}
+		})
+	}
+}
+
+// parseTestTodos parses lines as the tasks of a test.
+func parseTestTodos(t *testing.T, lines ...string) []*Todo {
+	t.Helper()
+	todos, err := ParseTodos(lines)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return todos
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,579 @@
+package todotxt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Predicate reports whether a task matches a filter.
+type Predicate func(*Todo) bool
+
+// QueryError describes a query syntax error. Column is 1-based and counts
+// bytes of the query string.
+type QueryError struct {
+	Column int
+	Msg    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+// CompileQuery compiles a filter expression into a Predicate.
+//
+// A query is a boolean combination of terms joined with "and", "or" and
+// "not" (case-insensitive) and grouped with parentheses. Adjacent terms
+// without an operator are joined with "and", and "and" binds tighter than
+// "or". Terms are:
+//
+//	+Project            task has the project
+//	@context            task has the context
+//	word, "a phrase"    text appears in the description, a project or a context
+//	key:value           field or tag equals value
+//	key OP value        field or tag compares to value, OP is = != < <= > >=
+//	is:done, is:open    task is (not) complete
+//	is:overdue          task is open and its due date has passed
+//	is:deferred         task is open and its t: date is after today
+//	has:key             task has the tag
+//
+// Fields are pri (priority letter), created and completed (dates); every
+// other key is looked up in the tags. Field names are case-insensitive and
+// tag keys are not. A word that is not a tag by the rules of a task line,
+// such as a URL, is a text search. Values compare as dates when both
+// sides are dates ("today", "tomorrow" and "yesterday" are accepted), then
+// as durations such as 90m, 2h or 1d, then as numbers, and otherwise as
+// strings.
+//
+//	+Work and @office and not pri:C and due<=2025-02-01 or est>2h
+func CompileQuery(query string) (Predicate, error) {
+	p := &queryParser{query: query, tokens: lexQuery(query)}
+	if p.peek().kind == qEOF {
+		return func(*Todo) bool { return true }, nil
+	}
+
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != qEOF {
+		return nil, p.errorAt(tok, "unexpected %q", tok.text)
+	}
+	return pred, nil
+}
+
+func FilterTodos(todos []*Todo, pred Predicate) []*Todo {
+	var results []*Todo
+	for _, todo := range todos {
+		if pred(todo) {
+			results = append(results, todo)
+		}
+	}
+	return results
+}
+
+func (tf *TodoFile) Filter(pred Predicate) []*Todo {
+	return FilterTodos(tf.Todos, pred)
+}
+
+// Query returns the tasks matching a CompileQuery expression.
+func (tf *TodoFile) Query(query string) ([]*Todo, error) {
+	pred, err := CompileQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return tf.Filter(pred), nil
+}
+
+func HasProject(project string) Predicate {
+	return func(t *Todo) bool {
+		return containsString(t.Projects, project)
+	}
+}
+
+func HasContext(context string) Predicate {
+	return func(t *Todo) bool {
+		return containsString(t.Contexts, context)
+	}
+}
+
+// ContainsText matches tasks whose description, projects or contexts
+// contain text, ignoring case.
+func ContainsText(text string) Predicate {
+	text = strings.ToLower(text)
+	return func(t *Todo) bool {
+		if strings.Contains(strings.ToLower(t.Description), text) {
+			return true
+		}
+		for _, project := range t.Projects {
+			if strings.Contains(strings.ToLower(project), text) {
+				return true
+			}
+		}
+		for _, context := range t.Contexts {
+			if strings.Contains(strings.ToLower(context), text) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsDeferred matches open tasks whose t: threshold date is after the day
+// of now.
+func IsDeferred(now time.Time) Predicate {
+	today := civilDate(now)
+	return func(t *Todo) bool {
+		if t.Complete {
+			return false
+		}
+		threshold := t.GetThresholdDate()
+		return threshold != nil && threshold.After(today)
+	}
+}
+
+// IsOverdue matches open tasks whose due date is before the day of now.
+func IsOverdue(now time.Time) Predicate {
+	today := civilDate(now)
+	return func(t *Todo) bool {
+		if t.Complete {
+			return false
+		}
+		due := t.GetDueDate()
+		return due != nil && due.Before(today)
+	}
+}
+
+func Not(pred Predicate) Predicate {
+	return func(t *Todo) bool { return !pred(t) }
+}
+
+func And(preds ...Predicate) Predicate {
+	return func(t *Todo) bool {
+		for _, pred := range preds {
+			if !pred(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func Or(preds ...Predicate) Predicate {
+	return func(t *Todo) bool {
+		for _, pred := range preds {
+			if pred(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type queryTokenKind int
+
+const (
+	qEOF queryTokenKind = iota
+	qWord
+	qString
+	qUnterminated
+	qOp
+	qLParen
+	qRParen
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+func lexQuery(query string) []queryToken {
+	var tokens []queryToken
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{qLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{qRParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(query) && query[i] != '"' {
+				if query[i] == '\\' && i+1 < len(query) {
+					i++
+				}
+				b.WriteByte(query[i])
+				i++
+			}
+			kind := qUnterminated
+			if i < len(query) {
+				kind = qString
+				i++
+			}
+			tokens = append(tokens, queryToken{kind, b.String(), start})
+		case startsQueryOp(query, i):
+			start := i
+			i++
+			if i < len(query) && query[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, queryToken{qOp, query[start:i], start})
+		default:
+			start := i
+			for i < len(query) && isWordChar(query[i]) {
+				if query[i] == '!' && i+1 < len(query) && query[i+1] == '=' {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, queryToken{qWord, query[start:i], start})
+		}
+	}
+	return append(tokens, queryToken{qEOF, "", len(query)})
+}
+
+// startsQueryOp reports whether an operator starts at query[i]. A '!' is
+// only an operator when it starts "!=" or a term, so "urgent!" is a word.
+func startsQueryOp(query string, i int) bool {
+	c := query[i]
+	if c != '!' {
+		return isQueryOpChar(c)
+	}
+	if i+1 < len(query) && query[i+1] == '=' {
+		return true
+	}
+	return i == 0 || !isWordChar(query[i-1])
+}
+
+func isQueryOpChar(c byte) bool {
+	return c == '<' || c == '>' || c == '=' || c == '!'
+}
+
+// isWordChar reports whether c continues a word. A '!' inside a word is
+// part of it unless it starts "!=".
+func isWordChar(c byte) bool {
+	if isSpace(c) || c == '(' || c == ')' || c == '"' {
+		return false
+	}
+	return c == '!' || !isQueryOpChar(c)
+}
+
+type queryParser struct {
+	query  string
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != qEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) errorAt(tok queryToken, format string, args ...any) error {
+	return &QueryError{Column: tok.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isKeyword(tok queryToken, keyword string) bool {
+	return tok.kind == qWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *queryParser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	preds := []Predicate{left}
+	for isKeyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, right)
+	}
+	if len(preds) == 1 {
+		return left, nil
+	}
+	return Or(preds...), nil
+}
+
+func (p *queryParser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	preds := []Predicate{left}
+	for {
+		tok := p.peek()
+		if isKeyword(tok, "and") {
+			p.next()
+		} else if tok.kind == qEOF || tok.kind == qRParen || isKeyword(tok, "or") {
+			break
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, right)
+	}
+	if len(preds) == 1 {
+		return left, nil
+	}
+	return And(preds...), nil
+}
+
+func (p *queryParser) parseUnary() (Predicate, error) {
+	tok := p.peek()
+	if isKeyword(tok, "not") || (tok.kind == qOp && tok.text == "!") {
+		p.next()
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(pred), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Predicate, error) {
+	tok := p.next()
+	switch tok.kind {
+	case qEOF:
+		return nil, p.errorAt(tok, "unexpected end of query")
+	case qLParen:
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != qRParen {
+			return nil, p.errorAt(closing, "expected ')'")
+		}
+		return pred, nil
+	case qRParen:
+		return nil, p.errorAt(tok, "unexpected ')'")
+	case qOp:
+		return nil, p.errorAt(tok, "unexpected operator %q", tok.text)
+	case qUnterminated:
+		return nil, p.errorAt(tok, "unterminated string")
+	case qString:
+		return ContainsText(tok.text), nil
+	}
+
+	if isKeyword(tok, "and") || isKeyword(tok, "or") {
+		return nil, p.errorAt(tok, "unexpected %q", tok.text)
+	}
+
+	word := tok.text
+	switch {
+	case len(word) > 1 && word[0] == '+':
+		return HasProject(word[1:]), nil
+	case len(word) > 1 && word[0] == '@':
+		return HasContext(word[1:]), nil
+	}
+
+	if op := p.peek(); op.kind == qOp {
+		p.next()
+		if !isTagKey(word) {
+			return nil, p.errorAt(tok, "invalid field name %q", word)
+		}
+		if op.text == "!" {
+			return nil, p.errorAt(op, "unexpected operator %q", op.text)
+		}
+		value := p.next()
+		if value.kind != qWord && value.kind != qString {
+			return nil, p.errorAt(value, "expected a value after %q", op.text)
+		}
+		return p.comparison(tok, word, op.text, value)
+	}
+
+	if key, value, found := strings.Cut(word, ":"); found && isTagKey(key) && value == "" {
+		return nil, p.errorAt(queryToken{pos: tok.pos + len(word)}, "expected a value after ':'")
+	}
+	// Words that are not tags in a task, such as URLs, are searched for.
+	if key, value, ok := splitTag(word); ok {
+		valueTok := queryToken{kind: qWord, text: value, pos: tok.pos + len(key) + 1}
+		return p.comparison(tok, key, ":", valueTok)
+	}
+
+	return ContainsText(word), nil
+}
+
+func (p *queryParser) comparison(keyTok queryToken, key, op string, valueTok queryToken) (Predicate, error) {
+	value := valueTok.text
+	// Field names are case-insensitive; tag keys are not.
+	switch lower := strings.ToLower(key); lower {
+	case "is", "has", "pri", "priority", "created", "completed":
+		key = lower
+	}
+
+	switch key {
+	case "is":
+		if op != ":" && op != "=" {
+			return nil, p.errorAt(keyTok, "is: only supports ':'")
+		}
+		switch strings.ToLower(value) {
+		case "done", "complete", "completed":
+			return func(t *Todo) bool { return t.Complete }, nil
+		case "open", "incomplete":
+			return func(t *Todo) bool { return !t.Complete }, nil
+		case "overdue":
+			return func(t *Todo) bool { return IsOverdue(Now())(t) }, nil
+		case "deferred":
+			return func(t *Todo) bool { return IsDeferred(Now())(t) }, nil
+		}
+		return nil, p.errorAt(valueTok, "unknown state %q (want done, open, overdue or deferred)", value)
+	case "has":
+		if op != ":" && op != "=" {
+			return nil, p.errorAt(keyTok, "has: only supports ':'")
+		}
+		return func(t *Todo) bool {
+			_, ok := t.Tags[value]
+			return ok
+		}, nil
+	case "pri", "priority":
+		want := strings.ToUpper(value)
+		if want != "NONE" && (len(want) != 1 || want[0] < 'A' || want[0] > 'Z') {
+			return nil, p.errorAt(valueTok, "invalid priority %q (must be A-Z or none)", value)
+		}
+		return func(t *Todo) bool {
+			got := "NONE"
+			if t.Priority != PriorityNone {
+				got = string(rune(t.Priority))
+			}
+			if (got == "NONE" || want == "NONE") && op != ":" && op != "=" && op != "!=" {
+				return false
+			}
+			return compareOrdered(strings.Compare(got, want), op)
+		}, nil
+	case "created", "completed":
+		if _, ok := parseQueryDate(value, Now()); !ok {
+			return nil, p.errorAt(valueTok, "invalid date %q", value)
+		}
+		return func(t *Todo) bool {
+			date := t.CreationDate
+			if key == "completed" {
+				date = t.CompletionDate
+			}
+			if date == nil {
+				return op == "!="
+			}
+			want, _ := parseQueryDate(value, Now())
+			return compareOrdered(date.Compare(want), op)
+		}, nil
+	}
+
+	return func(t *Todo) bool {
+		got, ok := t.Tags[key]
+		if !ok {
+			return op == "!="
+		}
+		return compareValues(got, op, value)
+	}, nil
+}
+
+// compareValues compares a tag value against a query value, resolving
+// relative dates in the query value first when the tag value is a date.
+func compareValues(got, op, want string) bool {
+	if _, ok := parseDate(got); ok {
+		if wantDate, ok := parseQueryDate(want, Now()); ok {
+			want = wantDate.Format("2006-01-02")
+		}
+	}
+	return compareOrdered(compareTagValues(got, want), op)
+}
+
+// compareTagValues orders two tag values using the most specific
+// interpretation that fits both: dates, then durations, then numbers, and
+// otherwise strings.
+func compareTagValues(a, b string) int {
+	if aDate, ok := parseDate(a); ok {
+		if bDate, ok := parseDate(b); ok {
+			return aDate.Compare(bDate)
+		}
+	}
+	if aDur, ok := parseQueryDuration(a); ok {
+		if bDur, ok := parseQueryDuration(b); ok {
+			return compareNumbers(float64(aDur), float64(bDur))
+		}
+	}
+	if aNum, err := strconv.ParseFloat(a, 64); err == nil {
+		if bNum, err := strconv.ParseFloat(b, 64); err == nil {
+			return compareNumbers(aNum, bNum)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareOrdered(cmp int, op string) bool {
+	switch op {
+	case ":", "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// parseQueryDate accepts the dates of ParseDate, including relative ones.
+func parseQueryDate(s string, now time.Time) (time.Time, bool) {
+	date, err := ParseDate(s, now)
+	return date, err == nil
+}
+
+// parseQueryDuration accepts Go durations plus d (days) and w (weeks).
+func parseQueryDuration(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	unit := s[len(s)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil {
+			return 0, false
+		}
+		day := 24 * time.Hour
+		if unit == 'w' {
+			day *= 7
+		}
+		return time.Duration(n * float64(day)), true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,134 @@
+package todotxt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCompileQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []int
+	}{
+		{"", []int{1, 2, 3, 4, 5, 6}},
+		{"+Work", []int{1, 2, 3}},
+		{"@office", []int{1, 2}},
+		{"milk", []int{4}},
+		{"MILK", []int{4}},
+		{"+Work @office", []int{1, 2}},
+		{"+Work and @office and not pri:C", []int{1}},
+		{"+Work and @office and not pri:C and due<=2025-02-01 or est>2h", []int{1, 3}},
+		{"@home or @store", []int{3, 4}},
+		{"not (+Work or +Home)", []int{4, 6}},
+		{"pri<=B", []int{1, 3}},
+		{"pri:none", []int{4, 5, 6}},
+		{"pri != A and +Work", []int{2, 3}},
+		{"due > 2025-01-31", []int{2}},
+		{"est>=90m", []int{3, 6}},
+		{"has:due", []int{1, 2}},
+		{"is:done", []int{5}},
+		{"is:open and @phone", nil},
+		{"completed:2025-01-09", []int{5}},
+		{"created<2025-01-02", []int{5}},
+		{"created<=today and due>-100y", nil},
+		{"due<+100y", []int{1, 2}},
+		{"is:overdue", nil},
+		{"due<tomorrow", []int{1}},
+		{"due<=eom", []int{1}},
+		{"due>=next-month or due<=yesterday", []int{2}},
+		{"\"plan sprint\"", []int{1}},
+		{"example.com", []int{6}},
+		{"https://example.com", []int{6}},
+		{"myKey:x", []int{4}},
+		{"mykey:x", nil},
+		{"has:myKey", []int{4}},
+		{"PRI:A or Is:done", []int{1, 5}},
+		{"! +Work and not @store and ! +Study", []int{5}},
+	}
+
+	// Monday morning in Tokyo, when task 1 is due.
+	defer SetClock(SetClock(FixedClock(time.Date(2025, 1, 20, 8, 0, 0, 0, time.FixedZone("JST", 9*60*60)))))
+
+	todos := parseTestTodos(t,
+		"(A) Plan sprint +Work @office due:2025-01-20 est:1h",
+		"(C) File expenses +Work @office due:2025-02-15",
+		"(B) Write report +Work @home est:3h",
+		"Buy milk @store myKey:x",
+		"x 2025-01-09 2025-01-01 Call plumber +Home @phone",
+		"Read https://example.com +Study est:90m",
+	)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			pred, err := CompileQuery(tt.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var got []int
+			for _, todo := range FilterTodos(todos, pred) {
+				got = append(got, todo.ID)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected IDs %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Expected IDs %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		column int
+	}{
+		{"+Work and", 10},
+		{"(+Work or @home", 16},
+		{"+Work )", 7},
+		{"due<=", 6},
+		{"pri:AB", 5},
+		{"\"unterminated", 1},
+		{"or +Work", 1},
+		{"+Work <= 3", 7},
+		{"is:later", 4},
+		{"created>someday", 9},
+		{"due:", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := CompileQuery(tt.query)
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("Expected QueryError, got %v", err)
+			}
+			if queryErr.Column != tt.column {
+				t.Errorf("Expected error at column %d, got %d (%v)", tt.column, queryErr.Column, err)
+			}
+		})
+	}
+}
+
+func TestTodoFileQuery(t *testing.T) {
+	tf := NewTodoFile("test.txt")
+	tf.Todos = parseTestTodos(t,
+		"(A) Plan sprint +Work est:1h",
+		"Read https://example.com est:3h",
+		"(B) Write report +Work @home est:3h",
+	)
+
+	results, err := tf.Query("+Work and est>2h")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 3 {
+		t.Errorf("Expected task 3, got %v", results)
+	}
+
+	if _, err := tf.Query("+Work and"); err == nil {
+		t.Error("Expected error for incomplete query")
+	}
+}
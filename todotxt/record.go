@@ -0,0 +1,141 @@
+package todotxt
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats accepted by WriteRecords.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+)
+
+// Record is the machine-readable form of a Todo used by WriteRecords:
+//
+//	id               line number in the todo file
+//	complete         whether the task is done
+//	priority         "A"-"Z", omitted when the task has none
+//	creation_date    YYYY-MM-DD, omitted when unset
+//	completion_date  YYYY-MM-DD, omitted when unset
+//	description      text without projects, contexts and tags
+//	projects         project names without '+', in line order
+//	contexts         context names without '@', in line order
+//	tags             key:value tags, including id: for stable IDs
+//	text             the task's todo.txt line
+type Record struct {
+	ID             int               `json:"id"`
+	Complete       bool              `json:"complete"`
+	Priority       string            `json:"priority,omitempty"`
+	CreationDate   string            `json:"creation_date,omitempty"`
+	CompletionDate string            `json:"completion_date,omitempty"`
+	Description    string            `json:"description"`
+	Projects       []string          `json:"projects"`
+	Contexts       []string          `json:"contexts"`
+	Tags           map[string]string `json:"tags"`
+	Text           string            `json:"text"`
+}
+
+// RecordColumns is the header of CSV and TSV output. Projects and contexts
+// are space-separated names and tags are space-separated key:value pairs
+// sorted by key; empty cells stand for unset values.
+var RecordColumns = []string{
+	"id", "complete", "priority", "creation_date", "completion_date",
+	"description", "projects", "contexts", "tags", "text",
+}
+
+func (t *Todo) Record() Record {
+	r := Record{
+		ID:          t.ID,
+		Complete:    t.Complete,
+		Description: t.Description,
+		Projects:    append([]string{}, t.Projects...),
+		Contexts:    append([]string{}, t.Contexts...),
+		Tags:        make(map[string]string, len(t.Tags)),
+		Text:        t.String(),
+	}
+	if t.Priority != PriorityNone {
+		r.Priority = string(rune(t.Priority))
+	}
+	if t.CreationDate != nil {
+		r.CreationDate = t.CreationDate.Format("2006-01-02")
+	}
+	if t.CompletionDate != nil {
+		r.CompletionDate = t.CompletionDate.Format("2006-01-02")
+	}
+	for key, value := range t.Tags {
+		r.Tags[key] = value
+	}
+	return r
+}
+
+func (r Record) columns() []string {
+	var tags []string
+	for key, value := range r.Tags {
+		tags = append(tags, key+":"+value)
+	}
+	sort.Strings(tags)
+
+	return []string{
+		strconv.Itoa(r.ID),
+		strconv.FormatBool(r.Complete),
+		r.Priority,
+		r.CreationDate,
+		r.CompletionDate,
+		r.Description,
+		strings.Join(r.Projects, " "),
+		strings.Join(r.Contexts, " "),
+		strings.Join(tags, " "),
+		r.Text,
+	}
+}
+
+// WriteRecords writes todos to w as a JSON array, newline-delimited JSON,
+// or CSV or TSV with a RecordColumns header.
+func WriteRecords(w io.Writer, format string, todos []*Todo) error {
+	records := make([]Record, 0, len(todos))
+	for _, todo := range todos {
+		records = append(records, todo.Record())
+	}
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV, FormatTSV:
+		rows := [][]string{RecordColumns}
+		for _, r := range records {
+			rows = append(rows, r.columns())
+		}
+		return WriteTable(w, format, rows)
+	}
+	return fmt.Errorf("invalid format: %s (must be json, ndjson, csv or tsv)", format)
+}
+
+// WriteTable writes rows, the first being the header, as CSV or TSV.
+func WriteTable(w io.Writer, format string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if format == FormatTSV {
+		writer.Comma = '\t'
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", format, err)
+	}
+	return nil
+}
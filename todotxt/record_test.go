@@ -0,0 +1,124 @@
+package todotxt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTodoRecord(t *testing.T) {
+	todos := parseTestTodos(t,
+		"(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa",
+		"x 2025-01-09 Buy milk, eggs @store",
+	)
+
+	r := todos[0].Record()
+	if r.ID != 1 || r.Complete || r.Priority != "A" || r.CreationDate != "2025-01-08" {
+		t.Errorf("Unexpected record header fields: %+v", r)
+	}
+	if r.Description != "Call Mom" || r.Tags["due"] != "2025-01-15" || r.Tags["id"] != "k3m9qa" {
+		t.Errorf("Unexpected record body fields: %+v", r)
+	}
+	if r.Text != "(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa" {
+		t.Errorf("Unexpected text: %s", r.Text)
+	}
+
+	r = todos[1].Record()
+	if !r.Complete || r.Priority != "" || r.CompletionDate != "2025-01-09" || len(r.Projects) != 0 {
+		t.Errorf("Unexpected completed record: %+v", r)
+	}
+}
+
+func TestWriteRecordsJSON(t *testing.T) {
+	todos := parseTestTodos(t,
+		"(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa",
+		"x 2025-01-09 Buy milk, eggs @store",
+	)
+
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, FormatJSON, todos); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output should be a JSON array: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(decoded))
+	}
+	if decoded[0]["priority"] != "A" {
+		t.Errorf("Expected priority A, got %v", decoded[0]["priority"])
+	}
+	if _, ok := decoded[1]["priority"]; ok {
+		t.Error("Unset priority should be omitted")
+	}
+	if projects, ok := decoded[1]["projects"].([]any); !ok || len(projects) != 0 {
+		t.Errorf("Projects should be an empty array, got %v", decoded[1]["projects"])
+	}
+
+	buf.Reset()
+	if err := WriteRecords(&buf, FormatJSON, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("No todos should encode as an empty array, got %s", buf.String())
+	}
+}
+
+func TestWriteRecordsNDJSON(t *testing.T) {
+	todos := parseTestTodos(t,
+		"(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa",
+		"x 2025-01-09 Buy milk, eggs @store",
+	)
+
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, FormatNDJSON, todos); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("Each line should be a JSON object: %v", err)
+		}
+	}
+}
+
+func TestWriteRecordsCSV(t *testing.T) {
+	todos := parseTestTodos(t,
+		"(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa",
+		"x 2025-01-09 Buy milk, eggs @store",
+	)
+
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, FormatCSV, todos); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "id,complete,priority,creation_date,completion_date,description,projects,contexts,tags,text\n" +
+		"1,false,A,2025-01-08,,Call Mom,Family,phone,due:2025-01-15 id:k3m9qa,(A) 2025-01-08 Call Mom +Family @phone due:2025-01-15 id:k3m9qa\n" +
+		"2,true,,,2025-01-09,\"Buy milk, eggs\",,store,,\"x 2025-01-09 Buy milk, eggs @store\"\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteRecords(&buf, FormatTSV, todos); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "id\tcomplete\tpriority\t") {
+		t.Errorf("Unexpected TSV header:\n%s", buf.String())
+	}
+}
+
+func TestWriteRecordsInvalidFormat(t *testing.T) {
+	if err := WriteRecords(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,180 @@
+package todotxt
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RecurrenceTag is the tag key of recurring tasks.
+const RecurrenceTag = "rec"
+
+// Recurrence is a parsed rec: value such as "1w", "+1m" or "2b". Units
+// are days (d), weeks (w), months (m), years (y) and business days (b).
+// A strict recurrence, written with a leading '+', advances from the
+// task's due date; otherwise it advances from the completion date.
+type Recurrence struct {
+	Interval int
+	Unit     byte
+	Strict   bool
+}
+
+// ParseRecurrence parses the value of a rec: tag.
+func ParseRecurrence(value string) (Recurrence, error) {
+	var r Recurrence
+	s := value
+	if len(s) > 0 && s[0] == '+' {
+		r.Strict = true
+		s = s[1:]
+	}
+	if len(s) < 2 {
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q (want e.g. 1w, +1m or 2b)", value)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 || s[0] == '+' || s[0] == '-' {
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q (want e.g. 1w, +1m or 2b)", value)
+	}
+	r.Interval = n
+
+	switch unit := s[len(s)-1]; unit {
+	case 'd', 'w', 'm', 'y', 'b':
+		r.Unit = unit
+	case 'D', 'W', 'M', 'Y', 'B':
+		r.Unit = unit + 'a' - 'A'
+	default:
+		return Recurrence{}, fmt.Errorf("invalid recurrence unit %q in %q (want d, w, m, y or b)", unit, value)
+	}
+	return r, nil
+}
+
+func (r Recurrence) String() string {
+	s := strconv.Itoa(r.Interval) + string(r.Unit)
+	if r.Strict {
+		s = "+" + s
+	}
+	return s
+}
+
+// Next returns the date one interval after date, or before it for a
+// negative interval. Months and years that overflow the target month end
+// on its last day, and business days skip Saturdays and Sundays.
+func (r Recurrence) Next(date time.Time) time.Time {
+	switch r.Unit {
+	case 'd':
+		return date.AddDate(0, 0, r.Interval)
+	case 'w':
+		return date.AddDate(0, 0, 7*r.Interval)
+	case 'm':
+		return addMonths(date, r.Interval)
+	case 'y':
+		return addMonths(date, 12*r.Interval)
+	case 'b':
+		step, n := 1, r.Interval
+		if n < 0 {
+			step, n = -1, -n
+		}
+		for n > 0 {
+			date = date.AddDate(0, 0, step)
+			if wd := date.Weekday(); wd != time.Saturday && wd != time.Sunday {
+				n--
+			}
+		}
+	}
+	return date
+}
+
+func addMonths(date time.Time, months int) time.Time {
+	year, month, day := date.Date()
+	first := time.Date(year, month+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+}
+
+// Recur returns the next occurrence of a task with a rec: tag that is
+// completed on the given date, or nil if the task does not recur. Call it
+// before MarkComplete, which removes the priority.
+//
+// The new task keeps the text, priority, projects, contexts and tags of
+// t, except for its id: tag. Its due date is one interval after the
+// completion date, or after the old due date for strict recurrences; a
+// t: threshold date moves by the same number of days. Without a due date
+// the t: date is advanced the same way, and without either the new task
+// is due one interval after completion. A creation date is set to the
+// completion date.
+func (t *Todo) Recur(completed time.Time) (*Todo, error) {
+	value, ok := t.Tags[RecurrenceTag]
+	if !ok {
+		return nil, nil
+	}
+	r, err := ParseRecurrence(value)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := ParseTodo(t.String())
+	if err != nil {
+		return nil, err
+	}
+	next.MarkUncomplete()
+	delete(next.Tags, IDTag)
+
+	today := civilDate(completed)
+	if next.CreationDate != nil {
+		next.CreationDate = &today
+	}
+
+	due, hasDue, err := tagDate(next, "due")
+	if err != nil {
+		return nil, err
+	}
+	threshold, hasThreshold, err := tagDate(next, ThresholdTag)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasDue:
+		base := today
+		if r.Strict {
+			base = due
+		}
+		newDue := r.Next(base)
+		next.Tags["due"] = newDue.Format("2006-01-02")
+		if hasThreshold {
+			days := int(newDue.Sub(due).Hours() / 24)
+			next.Tags[ThresholdTag] = threshold.AddDate(0, 0, days).Format("2006-01-02")
+		}
+	case hasThreshold:
+		base := today
+		if r.Strict {
+			base = threshold
+		}
+		next.Tags[ThresholdTag] = r.Next(base).Format("2006-01-02")
+	default:
+		next.Tags["due"] = r.Next(today).Format("2006-01-02")
+	}
+
+	return next, nil
+}
+
+// civilDate returns the calendar date of t as midnight UTC, so dates can
+// be compared with those parsed from tags.
+func civilDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func tagDate(t *Todo, key string) (time.Time, bool, error) {
+	value, ok := t.Tags[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	date, ok := parseDate(value)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("invalid %s date %q", key, value)
+	}
+	return date, true, nil
+}
@@ -0,0 +1,160 @@
+package todotxt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Recurrence
+		wantErr  bool
+	}{
+		{input: "1w", expected: Recurrence{Interval: 1, Unit: 'w'}},
+		{input: "+1m", expected: Recurrence{Interval: 1, Unit: 'm', Strict: true}},
+		{input: "2b", expected: Recurrence{Interval: 2, Unit: 'b'}},
+		{input: "10D", expected: Recurrence{Interval: 10, Unit: 'd'}},
+		{input: "+3y", expected: Recurrence{Interval: 3, Unit: 'y', Strict: true}},
+		{input: "w", wantErr: true},
+		{input: "0d", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "++1d", wantErr: true},
+		{input: "1x", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseRecurrence(%q) = %+v, want an error", tt.input, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) failed: %v", tt.input, err)
+			}
+			if r != tt.expected {
+				t.Errorf("ParseRecurrence(%q) = %+v, want %+v", tt.input, r, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		rec      string
+		from     string
+		expected string
+	}{
+		{rec: "3d", from: "2025-01-30", expected: "2025-02-02"},
+		{rec: "1w", from: "2025-01-08", expected: "2025-01-15"},
+		{rec: "1m", from: "2025-01-15", expected: "2025-02-15"},
+		{rec: "1m", from: "2025-01-31", expected: "2025-02-28"},
+		{rec: "1m", from: "2024-01-31", expected: "2024-02-29"},
+		{rec: "2m", from: "2025-12-31", expected: "2026-02-28"},
+		{rec: "1y", from: "2024-02-29", expected: "2025-02-28"},
+		{rec: "1b", from: "2025-01-10", expected: "2025-01-13"}, // Friday to Monday
+		{rec: "2b", from: "2025-01-11", expected: "2025-01-14"}, // Saturday
+		{rec: "5b", from: "2025-01-08", expected: "2025-01-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rec+" from "+tt.from, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, _ := time.Parse("2006-01-02", tt.from)
+			if got := r.Next(from).Format("2006-01-02"); got != tt.expected {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTodoRecur(t *testing.T) {
+	completed := time.Date(2025, 1, 20, 18, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Not recurring",
+			input:    "(A) Call Mom due:2025-01-15",
+			expected: "",
+		},
+		{
+			name:     "Relative to completion",
+			input:    "(A) 2025-01-01 Water plants +Home @garden due:2025-01-15 rec:1w",
+			expected: "(A) 2025-01-20 Water plants +Home @garden due:2025-01-27 rec:1w",
+		},
+		{
+			name:     "Strict from due date",
+			input:    "(B) Pay rent due:2025-01-01 rec:+1m +Finance",
+			expected: "(B) Pay rent due:2025-02-01 rec:+1m +Finance",
+		},
+		{
+			name:     "Threshold moves with due date",
+			input:    "File report t:2025-01-10 due:2025-01-17 rec:+1w",
+			expected: "File report t:2025-01-17 due:2025-01-24 rec:+1w",
+		},
+		{
+			name:     "Threshold without due date",
+			input:    "Review goals t:2025-01-05 rec:2b",
+			expected: "Review goals t:2025-01-22 rec:2b",
+		},
+		{
+			name:     "No dates becomes due",
+			input:    "Stretch rec:1d",
+			expected: "Stretch rec:1d due:2025-01-21",
+		},
+		{
+			name:     "Stable id is dropped",
+			input:    "Backup laptop rec:1w id:abc123",
+			expected: "Backup laptop rec:1w due:2025-01-27",
+		},
+		{
+			name:    "Invalid recurrence",
+			input:   "Stretch rec:often",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid due date",
+			input:   "Stretch due:friday rec:1w",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo, _ := ParseTodo(tt.input)
+			next, err := todo.Recur(completed)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Recur() = %v, want an error", next)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Recur() failed: %v", err)
+			}
+
+			got := ""
+			if next != nil {
+				got = next.String()
+			}
+			if got != tt.expected {
+				t.Errorf("Recur() = %q, want %q", got, tt.expected)
+			}
+			if todo.String() != tt.input {
+				t.Errorf("Recur() modified the task: %q", todo.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package todotxt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SortBy int
+
+const (
+	SortByID SortBy = iota
+	SortByPriority
+	SortByCreationDate
+	SortByDueDate
+	SortByDescription
+	SortByComplete
+)
+
+func SortTodos(todos []*Todo, sortBy SortBy) {
+	switch sortBy {
+	case SortByPriority:
+		sort.Slice(todos, func(i, j int) bool {
+			if todos[i].Priority == PriorityNone && todos[j].Priority == PriorityNone {
+				return todos[i].ID < todos[j].ID
+			}
+			if todos[i].Priority == PriorityNone {
+				return false
+			}
+			if todos[j].Priority == PriorityNone {
+				return true
+			}
+			if todos[i].Priority == todos[j].Priority {
+				return todos[i].ID < todos[j].ID
+			}
+			return todos[i].Priority < todos[j].Priority
+		})
+	case SortByCreationDate:
+		sort.Slice(todos, func(i, j int) bool {
+			if todos[i].CreationDate == nil && todos[j].CreationDate == nil {
+				return todos[i].ID < todos[j].ID
+			}
+			if todos[i].CreationDate == nil {
+				return false
+			}
+			if todos[j].CreationDate == nil {
+				return true
+			}
+			if todos[i].CreationDate.Equal(*todos[j].CreationDate) {
+				return todos[i].ID < todos[j].ID
+			}
+			return todos[i].CreationDate.Before(*todos[j].CreationDate)
+		})
+	case SortByDueDate:
+		sort.Slice(todos, func(i, j int) bool {
+			iDue := todos[i].GetDueDate()
+			jDue := todos[j].GetDueDate()
+
+			if iDue == nil && jDue == nil {
+				return todos[i].ID < todos[j].ID
+			}
+			if iDue == nil {
+				return false
+			}
+			if jDue == nil {
+				return true
+			}
+			if iDue.Equal(*jDue) {
+				return todos[i].ID < todos[j].ID
+			}
+			return iDue.Before(*jDue)
+		})
+	case SortByDescription:
+		sort.Slice(todos, func(i, j int) bool {
+			if todos[i].Description == todos[j].Description {
+				return todos[i].ID < todos[j].ID
+			}
+			return todos[i].Description < todos[j].Description
+		})
+	case SortByComplete:
+		sort.Slice(todos, func(i, j int) bool {
+			if todos[i].Complete == todos[j].Complete {
+				return todos[i].ID < todos[j].ID
+			}
+			return !todos[i].Complete
+		})
+	default:
+		sort.Slice(todos, func(i, j int) bool {
+			return todos[i].ID < todos[j].ID
+		})
+	}
+}
+
+func FilterOverdue(todos []*Todo) []*Todo {
+	return FilterTodos(todos, IsOverdue(Now()))
+}
+
+func FilterToday(todos []*Todo) []*Todo {
+	var results []*Todo
+	today := Today()
+	tomorrow := today.AddDate(0, 0, 1)
+
+	for _, todo := range todos {
+		if !todo.Complete {
+			if due := todo.GetDueDate(); due != nil {
+				if !due.Before(today) && due.Before(tomorrow) {
+					results = append(results, todo)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+func FilterThisWeek(todos []*Todo) []*Todo {
+	var results []*Todo
+	today := Today()
+	weekFromNow := today.AddDate(0, 0, 7)
+
+	for _, todo := range todos {
+		if !todo.Complete {
+			if due := todo.GetDueDate(); due != nil {
+				if !due.Before(today) && due.Before(weekFromNow) {
+					results = append(results, todo)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+func GroupByProject(todos []*Todo) map[string][]*Todo {
+	groups := make(map[string][]*Todo)
+	groups["No Project"] = []*Todo{}
+
+	for _, todo := range todos {
+		if len(todo.Projects) == 0 {
+			groups["No Project"] = append(groups["No Project"], todo)
+		} else {
+			for _, project := range todo.Projects {
+				groups[project] = append(groups[project], todo)
+			}
+		}
+	}
+
+	if len(groups["No Project"]) == 0 {
+		delete(groups, "No Project")
+	}
+
+	return groups
+}
+
+func GroupByContext(todos []*Todo) map[string][]*Todo {
+	groups := make(map[string][]*Todo)
+	groups["No Context"] = []*Todo{}
+
+	for _, todo := range todos {
+		if len(todo.Contexts) == 0 {
+			groups["No Context"] = append(groups["No Context"], todo)
+		} else {
+			for _, context := range todo.Contexts {
+				groups[context] = append(groups[context], todo)
+			}
+		}
+	}
+
+	if len(groups["No Context"]) == 0 {
+		delete(groups, "No Context")
+	}
+
+	return groups
+}
+
+// SortKey is one level of a multi-key sort. Field is one of id,
+// priority, created, completed, due, description, complete, project or
+// context, or else the key of a tag.
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+var sortFieldAliases = map[string]string{
+	"id":          "id",
+	"line":        "id",
+	"pri":         "priority",
+	"priority":    "priority",
+	"created":     "created",
+	"creation":    "created",
+	"completed":   "completed",
+	"completion":  "completed",
+	"due":         "due",
+	"desc":        "description",
+	"description": "description",
+	"text":        "description",
+	"complete":    "complete",
+	"done":        "complete",
+	"status":      "complete",
+	"project":     "project",
+	"context":     "context",
+}
+
+// ParseSortKeys parses a comma-separated sort specification such as
+// "priority,due,-created". A leading '-' sorts that key descending and a
+// "tag:" prefix forces a tag key that shares its name with a field.
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{}
+		if strings.HasPrefix(part, "-") {
+			key.Descending = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+		if part == "" {
+			return nil, fmt.Errorf("empty sort key in %q", spec)
+		}
+
+		if tag, ok := strings.CutPrefix(part, "tag:"); ok {
+			if !isTagKey(tag) {
+				return nil, fmt.Errorf("invalid tag key %q", tag)
+			}
+			key.Field = "tag:" + tag
+		} else if field, ok := sortFieldAliases[strings.ToLower(part)]; ok {
+			key.Field = field
+		} else if isTagKey(part) {
+			key.Field = "tag:" + part
+		} else {
+			return nil, fmt.Errorf("invalid sort key %q", part)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SortTodosBy sorts by each key in turn and then by ID. Tasks missing a
+// value for a key sort after those that have one, in either direction.
+func SortTodosBy(todos []*Todo, keys []SortKey) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		for _, key := range keys {
+			if cmp := compareField(todos[i], todos[j], key); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return todos[i].ID < todos[j].ID
+	})
+}
+
+func compareField(a, b *Todo, key SortKey) int {
+	av, aok := sortValue(a, key.Field)
+	bv, bok := sortValue(b, key.Field)
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return 1
+	case !bok:
+		return -1
+	}
+
+	var cmp int
+	switch av := av.(type) {
+	case int:
+		cmp = compareNumbers(float64(av), float64(bv.(int)))
+	case time.Time:
+		cmp = av.Compare(bv.(time.Time))
+	case string:
+		if strings.HasPrefix(key.Field, "tag:") {
+			cmp = compareTagValues(av, bv.(string))
+		} else {
+			cmp = strings.Compare(strings.ToLower(av), strings.ToLower(bv.(string)))
+		}
+	}
+
+	if key.Descending {
+		return -cmp
+	}
+	return cmp
+}
+
+func sortValue(t *Todo, field string) (any, bool) {
+	switch field {
+	case "id":
+		return t.ID, true
+	case "priority":
+		return int(t.Priority), t.Priority != PriorityNone
+	case "created":
+		if t.CreationDate == nil {
+			return nil, false
+		}
+		return *t.CreationDate, true
+	case "completed":
+		if t.CompletionDate == nil {
+			return nil, false
+		}
+		return *t.CompletionDate, true
+	case "due":
+		if due := t.GetDueDate(); due != nil {
+			return *due, true
+		}
+		return nil, false
+	case "description":
+		return t.Description, true
+	case "complete":
+		if t.Complete {
+			return 1, true
+		}
+		return 0, true
+	case "project":
+		if len(t.Projects) == 0 {
+			return nil, false
+		}
+		return t.Projects[0], true
+	case "context":
+		if len(t.Contexts) == 0 {
+			return nil, false
+		}
+		return t.Contexts[0], true
+	}
+	value, ok := t.Tags[strings.TrimPrefix(field, "tag:")]
+	return value, ok
+}
+
+func GroupByPriority(todos []*Todo) map[string][]*Todo {
+	groups := make(map[string][]*Todo)
+	for _, todo := range todos {
+		name := "No Priority"
+		if todo.Priority != PriorityNone {
+			name = string(rune(todo.Priority))
+		}
+		groups[name] = append(groups[name], todo)
+	}
+	return groups
+}
+
+func GroupByTag(todos []*Todo, key string) map[string][]*Todo {
+	groups := make(map[string][]*Todo)
+	for _, todo := range todos {
+		name := "No " + key
+		if value, ok := todo.Tags[key]; ok {
+			name = value
+		}
+		groups[name] = append(groups[name], todo)
+	}
+	return groups
+}
+
+// Group is one section of grouped tasks. Name is the group's key, such as
+// a project name, and Label is how it is written in a task line, such as
+// "+Work". Groups for tasks without a value have an empty Name.
+type Group struct {
+	Name  string
+	Label string
+	Todos []*Todo
+}
+
+// GroupTodos groups tasks by "project", "context", "priority" or
+// "tag:<key>". Groups are ordered by name with the group of tasks lacking
+// a value last, and tasks keep their order within a group.
+func GroupTodos(todos []*Todo, by string) ([]Group, error) {
+	var groups map[string][]*Todo
+	var none, prefix string
+
+	switch {
+	case by == "project":
+		groups, none, prefix = GroupByProject(todos), "No Project", "+"
+	case by == "context":
+		groups, none, prefix = GroupByContext(todos), "No Context", "@"
+	case by == "priority":
+		groups, none = GroupByPriority(todos), "No Priority"
+	case strings.HasPrefix(by, "tag:") && isTagKey(by[4:]):
+		key := by[4:]
+		groups, none, prefix = GroupByTag(todos, key), "No "+key, key+":"
+	default:
+		return nil, fmt.Errorf("invalid group %q (must be project, context, priority or tag:<key>)", by)
+	}
+
+	var names []string
+	for name := range groups {
+		if name != none {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return compareTagValues(names[i], names[j]) < 0
+	})
+
+	var result []Group
+	for _, name := range names {
+		label := prefix + name
+		if by == "priority" {
+			label = "(" + name + ")"
+		}
+		result = append(result, Group{Name: name, Label: label, Todos: groups[name]})
+	}
+	if len(groups[none]) > 0 {
+		result = append(result, Group{Label: none, Todos: groups[none]})
+	}
+	return result, nil
+}
@@ -0,0 +1,294 @@
+package todotxt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortByPriority(t *testing.T) {
+	todos := []*Todo{
+		{ID: 1, Priority: PriorityC, Description: "Task C"},
+		{ID: 2, Priority: PriorityA, Description: "Task A"},
+		{ID: 3, Priority: PriorityNone, Description: "No priority"},
+		{ID: 4, Priority: PriorityB, Description: "Task B"},
+	}
+
+	SortTodos(todos, SortByPriority)
+
+	if todos[0].Priority != PriorityA {
+		t.Error("First task should have priority A")
+	}
+	if todos[1].Priority != PriorityB {
+		t.Error("Second task should have priority B")
+	}
+	if todos[2].Priority != PriorityC {
+		t.Error("Third task should have priority C")
+	}
+	if todos[3].Priority != PriorityNone {
+		t.Error("Last task should have no priority")
+	}
+}
+
+func TestSortByCreationDate(t *testing.T) {
+	date1 := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	date2 := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	date3 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	todos := []*Todo{
+		{ID: 1, CreationDate: &date1, Description: "Task 1"},
+		{ID: 2, CreationDate: &date2, Description: "Task 2"},
+		{ID: 3, CreationDate: nil, Description: "No date"},
+		{ID: 4, CreationDate: &date3, Description: "Task 3"},
+	}
+
+	SortTodos(todos, SortByCreationDate)
+
+	if !todos[0].CreationDate.Equal(date3) {
+		t.Error("First task should have earliest date")
+	}
+	if !todos[1].CreationDate.Equal(date1) {
+		t.Error("Second task should have middle date")
+	}
+	if !todos[2].CreationDate.Equal(date2) {
+		t.Error("Third task should have latest date")
+	}
+	if todos[3].CreationDate != nil {
+		t.Error("Last task should have no date")
+	}
+}
+
+func TestSortByDueDate(t *testing.T) {
+	todos := []*Todo{
+		{ID: 1, Description: "Task 1", Tags: map[string]string{"due": "2025-01-20"}},
+		{ID: 2, Description: "Task 2", Tags: map[string]string{"due": "2025-01-10"}},
+		{ID: 3, Description: "No due", Tags: map[string]string{}},
+		{ID: 4, Description: "Task 3", Tags: map[string]string{"due": "2025-01-15"}},
+	}
+
+	SortTodos(todos, SortByDueDate)
+
+	if todos[0].Tags["due"] != "2025-01-10" {
+		t.Error("First task should have earliest due date")
+	}
+	if todos[1].Tags["due"] != "2025-01-15" {
+		t.Error("Second task should have middle due date")
+	}
+	if todos[2].Tags["due"] != "2025-01-20" {
+		t.Error("Third task should have latest due date")
+	}
+	if _, hasDue := todos[3].Tags["due"]; hasDue {
+		t.Error("Last task should have no due date")
+	}
+}
+
+func TestFilterByDueDate(t *testing.T) {
+	todos := []*Todo{
+		{ID: 1, Complete: false, Description: "Yesterday", Tags: map[string]string{"due": "2025-01-09"}},
+		{ID: 2, Complete: false, Description: "Today", Tags: map[string]string{"due": "2025-01-10"}},
+		{ID: 3, Complete: false, Description: "Tomorrow", Tags: map[string]string{"due": "2025-01-11"}},
+		{ID: 4, Complete: false, Description: "In six days", Tags: map[string]string{"due": "2025-01-16"}},
+		{ID: 5, Complete: false, Description: "In a week", Tags: map[string]string{"due": "2025-01-17"}},
+		{ID: 6, Complete: true, Description: "Complete overdue", Tags: map[string]string{"due": "2025-01-09"}},
+		{ID: 7, Complete: false, Description: "No due", Tags: map[string]string{}},
+	}
+
+	jst := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		filter   func([]*Todo) []*Todo
+		expected []int
+	}{
+		// 08:00 JST is still the previous day in UTC.
+		{name: "Today in the morning", now: time.Date(2025, 1, 10, 8, 0, 0, 0, jst), filter: FilterToday, expected: []int{2}},
+		{name: "Today at night", now: time.Date(2025, 1, 10, 23, 30, 0, 0, jst), filter: FilterToday, expected: []int{2}},
+		{name: "Overdue in the morning", now: time.Date(2025, 1, 10, 8, 0, 0, 0, jst), filter: FilterOverdue, expected: []int{1}},
+		{name: "Overdue at night", now: time.Date(2025, 1, 10, 23, 30, 0, 0, jst), filter: FilterOverdue, expected: []int{1}},
+		{name: "This week", now: time.Date(2025, 1, 10, 8, 0, 0, 0, jst), filter: FilterThisWeek, expected: []int{2, 3, 4}},
+		{name: "Today in UTC", now: time.Date(2025, 1, 9, 23, 0, 0, 0, time.UTC), filter: FilterToday, expected: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer SetClock(SetClock(FixedClock(tt.now)))
+
+			var got []int
+			for _, todo := range tt.filter(todos) {
+				got = append(got, todo.ID)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got IDs %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGroupByProject(t *testing.T) {
+	todos := []*Todo{
+		{ID: 1, Description: "Task 1", Projects: []string{"Work"}},
+		{ID: 2, Description: "Task 2", Projects: []string{"Work", "Urgent"}},
+		{ID: 3, Description: "Task 3", Projects: []string{"Personal"}},
+		{ID: 4, Description: "Task 4", Projects: []string{}},
+	}
+
+	groups := GroupByProject(todos)
+
+	if len(groups["Work"]) != 2 {
+		t.Errorf("Expected 2 tasks in Work project, got %d", len(groups["Work"]))
+	}
+	if len(groups["Personal"]) != 1 {
+		t.Errorf("Expected 1 task in Personal project, got %d", len(groups["Personal"]))
+	}
+	if len(groups["Urgent"]) != 1 {
+		t.Errorf("Expected 1 task in Urgent project, got %d", len(groups["Urgent"]))
+	}
+	if len(groups["No Project"]) != 1 {
+		t.Errorf("Expected 1 task with no project, got %d", len(groups["No Project"]))
+	}
+}
+
+func TestGroupByContext(t *testing.T) {
+	todos := []*Todo{
+		{ID: 1, Description: "Task 1", Contexts: []string{"home"}},
+		{ID: 2, Description: "Task 2", Contexts: []string{"office", "meeting"}},
+		{ID: 3, Description: "Task 3", Contexts: []string{"office"}},
+		{ID: 4, Description: "Task 4", Contexts: []string{}},
+	}
+
+	groups := GroupByContext(todos)
+
+	if len(groups["office"]) != 2 {
+		t.Errorf("Expected 2 tasks in office context, got %d", len(groups["office"]))
+	}
+	if len(groups["home"]) != 1 {
+		t.Errorf("Expected 1 task in home context, got %d", len(groups["home"]))
+	}
+	if len(groups["meeting"]) != 1 {
+		t.Errorf("Expected 1 task in meeting context, got %d", len(groups["meeting"]))
+	}
+	if len(groups["No Context"]) != 1 {
+		t.Errorf("Expected 1 task with no context, got %d", len(groups["No Context"]))
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	keys, err := ParseSortKeys("priority,due,-created,est,tag:due")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []SortKey{
+		{Field: "priority"},
+		{Field: "due"},
+		{Field: "created", Descending: true},
+		{Field: "tag:est"},
+		{Field: "tag:due"},
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, keys)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], keys[i])
+		}
+	}
+
+	for _, spec := range []string{"", "priority,", "-", "10:30"} {
+		if _, err := ParseSortKeys(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestSortTodosBy(t *testing.T) {
+	lines := []string{
+		"(B) 2025-01-05 Task 1 due:2025-02-01",
+		"(A) 2025-01-03 Task 2",
+		"(B) 2025-01-08 Task 3 due:2025-01-15 est:30m",
+		"2025-01-01 Task 4 est:2h",
+		"(A) 2025-01-09 Task 5 due:2025-01-10 est:45m",
+	}
+	todos, _ := ParseTodos(lines)
+
+	ids := func() []int {
+		var result []int
+		for _, todo := range todos {
+			result = append(result, todo.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		spec     string
+		expected []int
+	}{
+		{"priority,due,-created", []int{5, 2, 3, 1, 4}},
+		{"-priority", []int{1, 3, 2, 5, 4}},
+		{"due", []int{5, 3, 1, 2, 4}},
+		{"-due", []int{1, 3, 5, 2, 4}},
+		{"est", []int{3, 5, 4, 1, 2}},
+		{"-est,id", []int{4, 5, 3, 1, 2}},
+		{"created", []int{4, 2, 1, 3, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			keys, err := ParseSortKeys(tt.spec)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			SortTodosBy(todos, keys)
+			got := ids()
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupTodos(t *testing.T) {
+	lines := []string{
+		"(A) Task 1 +Work @office est:2h",
+		"Task 2 +Home",
+		"(B) Task 3 +Work @home est:30m",
+		"Task 4",
+	}
+	todos, _ := ParseTodos(lines)
+
+	tests := []struct {
+		by       string
+		expected []string
+		counts   []int
+	}{
+		{"project", []string{"+Home", "+Work", "No Project"}, []int{1, 2, 1}},
+		{"context", []string{"@home", "@office", "No Context"}, []int{1, 1, 2}},
+		{"priority", []string{"(A)", "(B)", "No Priority"}, []int{1, 1, 2}},
+		{"tag:est", []string{"est:30m", "est:2h", "No est"}, []int{1, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			groups, err := GroupTodos(todos, tt.by)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(groups) != len(tt.expected) {
+				t.Fatalf("Expected %d groups, got %v", len(tt.expected), groups)
+			}
+			for i, group := range groups {
+				if group.Label != tt.expected[i] || len(group.Todos) != tt.counts[i] {
+					t.Errorf("Expected group %s with %d tasks, got %s with %d",
+						tt.expected[i], tt.counts[i], group.Label, len(group.Todos))
+				}
+			}
+		})
+	}
+
+	if _, err := GroupTodos(todos, "size"); err == nil {
+		t.Error("Expected error for unknown grouping")
+	}
+}
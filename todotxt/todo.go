@@ -1,8 +1,7 @@
-package main
+package todotxt
 
 import (
 	"fmt"
-	"strings"
 	"time"
 )
 
@@ -49,14 +48,19 @@ type Todo struct {
 	Contexts       []string
 	Tags           map[string]string
 	Raw            string
+
+	header   []token
+	tokens   []token
+	source   string
+	rendered string
 }
 
 func NewTodo(description string) *Todo {
-	now := time.Now()
+	today := Today()
 	return &Todo{
 		Complete:     false,
 		Priority:     PriorityNone,
-		CreationDate: &now,
+		CreationDate: &today,
 		Description:  description,
 		Projects:     []string{},
 		Contexts:     []string{},
@@ -65,51 +69,63 @@ func NewTodo(description string) *Todo {
 	}
 }
 
+// String formats the task as a todo.txt line. A parsed task that has not
+// been modified since parsing returns its original line unchanged; after
+// modification only the changed tokens differ from the original.
 func (t *Todo) String() string {
-	var parts []string
+	line := t.render()
+	if t.rendered != "" && line == t.rendered {
+		return t.source
+	}
+	return line
+}
+
+func (t *Todo) render() string {
+	var line []token
+	part := func(role, text string) {
+		line = append(line, t.headerToken(role, text))
+	}
 
 	if t.Complete {
-		parts = append(parts, "x")
+		part(headerMark, "x")
 		if t.CompletionDate != nil {
-			parts = append(parts, t.CompletionDate.Format("2006-01-02"))
+			part(headerCompletion, t.CompletionDate.Format("2006-01-02"))
 		}
 		if t.CreationDate != nil {
-			parts = append(parts, t.CreationDate.Format("2006-01-02"))
+			part(headerCreation, t.CreationDate.Format("2006-01-02"))
 		}
 	} else {
 		if t.Priority != PriorityNone {
-			parts = append(parts, fmt.Sprintf("(%c)", t.Priority))
+			part(headerPriority, fmt.Sprintf("(%c)", t.Priority))
 		}
 		if t.CreationDate != nil {
-			parts = append(parts, t.CreationDate.Format("2006-01-02"))
+			part(headerCreation, t.CreationDate.Format("2006-01-02"))
 		}
 	}
 
-	// Build full description with projects, contexts, and tags
-	fullDesc := t.Description
-
-	for _, project := range t.Projects {
-		if !strings.Contains(fullDesc, "+"+project) {
-			fullDesc += " +" + project
-		}
-	}
+	return renderTokens(append(line, t.syncTokens()...))
+}
 
-	for _, context := range t.Contexts {
-		if !strings.Contains(fullDesc, "@"+context) {
-			fullDesc += " @" + context
-		}
-	}
+// Roles of the header words, kept in token.key of Todo.header.
+const (
+	headerMark       = "x"
+	headerCompletion = "completion"
+	headerPriority   = "priority"
+	headerCreation   = "creation"
+)
 
-	for key, value := range t.Tags {
-		tag := fmt.Sprintf("%s:%s", key, value)
-		if !strings.Contains(fullDesc, tag) {
-			fullDesc += " " + tag
+// headerToken returns the header word for role, keeping the spacing of the
+// parsed word when its text is unchanged.
+func (t *Todo) headerToken(role, text string) token {
+	for _, tok := range t.header {
+		if tok.key == role && tok.text == text {
+			if tok.space == "" {
+				tok.space = " "
+			}
+			return tok
 		}
 	}
-
-	parts = append(parts, fullDesc)
-
-	return strings.Join(parts, " ")
+	return token{kind: tokenWord, space: " ", text: text, key: role}
 }
 
 func (t *Todo) SetPriority(p Priority) {
@@ -120,8 +136,8 @@ func (t *Todo) SetPriority(p Priority) {
 
 func (t *Todo) MarkComplete() {
 	t.Complete = true
-	now := time.Now()
-	t.CompletionDate = &now
+	today := Today()
+	t.CompletionDate = &today
 	t.Priority = PriorityNone
 }
 
@@ -160,3 +176,17 @@ func (t *Todo) GetDueDate() *time.Time {
 	}
 	return nil
 }
+
+// ThresholdTag is the tag key of a task's threshold (start) date.
+const ThresholdTag = "t"
+
+// GetThresholdDate returns the t: date before which the task is hidden
+// from the default views, or nil.
+func (t *Todo) GetThresholdDate() *time.Time {
+	if threshold, ok := t.Tags[ThresholdTag]; ok {
+		if date, err := time.Parse("2006-01-02", threshold); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
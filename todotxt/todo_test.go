@@ -0,0 +1,356 @@
+package todotxt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTodo(t *testing.T) {
+	// 08:00 in Tokyo is the previous day in UTC.
+	defer SetClock(SetClock(FixedClock(time.Date(2025, 1, 10, 8, 0, 0, 0, time.FixedZone("JST", 9*60*60)))))
+
+	todo := NewTodo("Test task")
+
+	if todo.Description != "Test task" {
+		t.Errorf("Expected description 'Test task', got '%s'", todo.Description)
+	}
+
+	if todo.Complete {
+		t.Error("New todo should not be complete")
+	}
+
+	if todo.Priority != PriorityNone {
+		t.Error("New todo should have no priority")
+	}
+
+	if todo.CreationDate == nil {
+		t.Fatal("New todo should have creation date")
+	}
+	if got := todo.String(); got != "2025-01-10 Test task" {
+		t.Errorf("Expected '2025-01-10 Test task', got '%s'", got)
+	}
+}
+
+func TestTodoString(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func() *Todo
+		expected string
+	}{
+		{
+			name: "Simple todo",
+			setup: func() *Todo {
+				return &Todo{
+					Description: "Simple task",
+				}
+			},
+			expected: "Simple task",
+		},
+		{
+			name: "Todo with priority",
+			setup: func() *Todo {
+				return &Todo{
+					Priority:    PriorityA,
+					Description: "Important task",
+				}
+			},
+			expected: "(A) Important task",
+		},
+		{
+			name: "Completed todo",
+			setup: func() *Todo {
+				todo := &Todo{
+					Complete:    true,
+					Description: "Finished task",
+				}
+				completionDate := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+				todo.CompletionDate = &completionDate
+				return todo
+			},
+			expected: "x 2025-01-09 Finished task",
+		},
+		{
+			name: "Todo with project and context",
+			setup: func() *Todo {
+				todo := &Todo{
+					Description: "Task with tags",
+					Projects:    []string{"Work"},
+					Contexts:    []string{"office"},
+				}
+				return todo
+			},
+			expected: "Task with tags +Work @office",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := tt.setup()
+			result := todo.String()
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTodoMarkComplete(t *testing.T) {
+	defer SetClock(SetClock(FixedClock(time.Date(2025, 1, 10, 23, 30, 0, 0, time.FixedZone("PST", -8*60*60)))))
+
+	todo := &Todo{
+		Priority:    PriorityA,
+		Description: "Test task",
+	}
+
+	todo.MarkComplete()
+
+	if !todo.Complete {
+		t.Error("Todo should be marked as complete")
+	}
+
+	if todo.Priority != PriorityNone {
+		t.Error("Completed todo should have no priority")
+	}
+
+	if todo.CompletionDate == nil {
+		t.Fatal("Completed todo should have completion date")
+	}
+	if got := todo.String(); got != "x 2025-01-10 Test task" {
+		t.Errorf("Expected 'x 2025-01-10 Test task', got '%s'", got)
+	}
+}
+
+func TestTodoMarkUncomplete(t *testing.T) {
+	todo := &Todo{
+		Complete:    true,
+		Description: "Test task",
+	}
+	completionDate := time.Now()
+	todo.CompletionDate = &completionDate
+
+	todo.MarkUncomplete()
+
+	if todo.Complete {
+		t.Error("Todo should not be complete")
+	}
+
+	if todo.CompletionDate != nil {
+		t.Error("Uncompleted todo should have no completion date")
+	}
+}
+
+func TestAddProject(t *testing.T) {
+	todo := &Todo{
+		Description: "Test task",
+		Projects:    []string{},
+	}
+
+	todo.AddProject("Work")
+	if len(todo.Projects) != 1 || todo.Projects[0] != "Work" {
+		t.Error("Project not added correctly")
+	}
+
+	todo.AddProject("Work")
+	if len(todo.Projects) != 1 {
+		t.Error("Duplicate project should not be added")
+	}
+
+	todo.AddProject("Personal")
+	if len(todo.Projects) != 2 || todo.Projects[1] != "Personal" {
+		t.Error("Second project not added correctly")
+	}
+}
+
+func TestAddContext(t *testing.T) {
+	todo := &Todo{
+		Description: "Test task",
+		Contexts:    []string{},
+	}
+
+	todo.AddContext("home")
+	if len(todo.Contexts) != 1 || todo.Contexts[0] != "home" {
+		t.Error("Context not added correctly")
+	}
+
+	todo.AddContext("home")
+	if len(todo.Contexts) != 1 {
+		t.Error("Duplicate context should not be added")
+	}
+}
+
+func TestAddTag(t *testing.T) {
+	todo := &Todo{
+		Description: "Test task",
+		Tags:        make(map[string]string),
+	}
+
+	todo.AddTag("due", "2025-01-15")
+	if todo.Tags["due"] != "2025-01-15" {
+		t.Error("Tag not added correctly")
+	}
+
+	todo.AddTag("due", "2025-01-20")
+	if todo.Tags["due"] != "2025-01-20" {
+		t.Error("Tag should be updated")
+	}
+}
+
+func TestGetDueDate(t *testing.T) {
+	todo := &Todo{
+		Description: "Test task",
+		Tags:        map[string]string{"due": "2025-01-15"},
+	}
+
+	dueDate := todo.GetDueDate()
+	if dueDate == nil {
+		t.Fatal("Due date should not be nil")
+	}
+
+	expected := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if !dueDate.Equal(expected) {
+		t.Errorf("Expected due date %v, got %v", expected, dueDate)
+	}
+
+	todo.Tags["due"] = "invalid-date"
+	if todo.GetDueDate() != nil {
+		t.Error("Invalid date should return nil")
+	}
+}
+
+func TestGetThresholdDate(t *testing.T) {
+	todo, _ := ParseTodo("Plan trip t:2025-03-01")
+
+	threshold := todo.GetThresholdDate()
+	if threshold == nil || !threshold.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected threshold 2025-03-01, got %v", threshold)
+	}
+
+	todo.Tags["t"] = "soon"
+	if todo.GetThresholdDate() != nil {
+		t.Error("Invalid date should return nil")
+	}
+}
+
+func TestTodoStringRoundTrip(t *testing.T) {
+	lines := []string{
+		"(A) Call Mom +Family @phone",
+		"x 2025-01-09 2025-01-08 Write tests +Work",
+		"2025-01-08 Pay rent   due:2025-02-01 +Home   @desk",
+		"(B)  Double  spaced\ttask",
+		"Tags z:1 in a:2 original m:3 order",
+		"  Leading whitespace",
+		"Trailing whitespace   ",
+		"+Project first then words @ctx last",
+		"Task due:2025-01-01 due:2025-02-01",
+		"(A)  2025-01-08\tIrregular header",
+	}
+
+	for _, line := range lines {
+		todo, err := ParseTodo(line)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := todo.String(); got != line {
+			t.Errorf("Round trip changed line:\n  want %q\n  got  %q", line, got)
+		}
+	}
+}
+
+func TestTodoStringMinimalEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		edit     func(*Todo)
+		expected string
+	}{
+		{
+			name:     "Change priority keeps body",
+			input:    "(A) Call  Mom z:1 +Family a:2",
+			edit:     func(todo *Todo) { todo.SetPriority(PriorityB) },
+			expected: "(B) Call  Mom z:1 +Family a:2",
+		},
+		{
+			name:     "Update tag in place",
+			input:    "Pay rent due:2025-02-01 +Home",
+			edit:     func(todo *Todo) { todo.AddTag("due", "2025-03-01") },
+			expected: "Pay rent due:2025-03-01 +Home",
+		},
+		{
+			name:     "New tokens are appended",
+			input:    "Pay rent z:1 +Home",
+			edit:     func(todo *Todo) { todo.AddContext("bank"); todo.AddTag("a", "2") },
+			expected: "Pay rent z:1 +Home @bank a:2",
+		},
+		{
+			name:     "Removed project is dropped",
+			input:    "Pay +Home rent @bank",
+			edit:     func(todo *Todo) { todo.Projects = nil },
+			expected: "Pay rent @bank",
+		},
+		{
+			name:  "Complete keeps tokens",
+			input: "(A) 2025-01-08 Write +Work  @office",
+			edit: func(todo *Todo) {
+				todo.MarkComplete()
+				completed := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+				todo.CompletionDate = &completed
+			},
+			expected: "x 2025-01-09 2025-01-08 Write +Work  @office",
+		},
+		{
+			name:     "Changed description keeps its position",
+			input:    "+Work Old text @office",
+			edit:     func(todo *Todo) { todo.Description = "New text" },
+			expected: "+Work New text @office",
+		},
+		{
+			name:     "Duplicate tag keys survive other edits",
+			input:    "Task due:2025-01-01 due:2025-02-01",
+			edit:     func(todo *Todo) { todo.SetPriority(PriorityA) },
+			expected: "(A) Task due:2025-01-01 due:2025-02-01",
+		},
+		{
+			name:     "Changed duplicate tag rewrites the last occurrence",
+			input:    "Task due:2025-01-01 x:1 due:2025-02-01",
+			edit:     func(todo *Todo) { todo.AddTag("due", "2025-03-01") },
+			expected: "Task due:2025-01-01 x:1 due:2025-03-01",
+		},
+		{
+			name:     "Removed duplicate tag drops every occurrence",
+			input:    "Task due:2025-01-01 x:1 due:2025-02-01",
+			edit:     func(todo *Todo) { delete(todo.Tags, "due") },
+			expected: "Task x:1",
+		},
+		{
+			name:     "Body edit keeps header spacing",
+			input:    "(A)  2025-01-08\tCall Mom",
+			edit:     func(todo *Todo) { todo.AddContext("phone") },
+			expected: "(A)  2025-01-08\tCall Mom @phone",
+		},
+		{
+			name:     "Changed priority keeps date spacing",
+			input:    "(A)  2025-01-08   Call Mom",
+			edit:     func(todo *Todo) { todo.SetPriority(PriorityC) },
+			expected: "(C)  2025-01-08   Call Mom",
+		},
+		{
+			name:     "Added priority keeps date spacing",
+			input:    "2025-01-08   Call Mom",
+			edit:     func(todo *Todo) { todo.SetPriority(PriorityB) },
+			expected: "(B) 2025-01-08   Call Mom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo, err := ParseTodo(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.edit(todo)
+			if got := todo.String(); got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
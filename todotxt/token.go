@@ -0,0 +1,277 @@
+package todotxt
+
+import (
+	"sort"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenProject
+	tokenContext
+	tokenTag
+)
+
+// token is one whitespace-separated word of a task line. It keeps the
+// whitespace that preceded it so an unmodified body renders byte for byte,
+// and its byte offset in the line for diagnostics.
+type token struct {
+	kind  tokenKind
+	pos   int
+	space string
+	text  string
+	key   string
+	value string
+}
+
+// scanWords splits a line into words on ASCII whitespace. Every word starts
+// at the beginning of the line or right after whitespace, which is the only
+// place the todo.txt format allows a project, context or tag to begin.
+func scanWords(s string) []token {
+	var words []token
+	i := 0
+	for i < len(s) {
+		start := i
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+		wordStart := i
+		for i < len(s) && !isSpace(s[i]) {
+			i++
+		}
+		words = append(words, token{
+			kind:  tokenWord,
+			pos:   wordStart,
+			space: s[start:wordStart],
+			text:  s[wordStart:i],
+		})
+	}
+	return words
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// classify decides what a body word is:
+//
+//   - "+name" is a project and "@name" a context, where name is non-empty;
+//   - "key:value" is a tag when key starts with a letter and holds only
+//     letters, digits, '-' and '_', value is non-empty, there is no second
+//     colon and value does not start with "//".
+//
+// Everything else, including URLs, e-mail addresses, "C++" and times like
+// "10:30", is a plain description word.
+func classify(tok token) token {
+	word := tok.text
+	switch {
+	case len(word) > 1 && word[0] == '+':
+		tok.kind = tokenProject
+		tok.value = word[1:]
+	case len(word) > 1 && word[0] == '@':
+		tok.kind = tokenContext
+		tok.value = word[1:]
+	default:
+		if key, value, ok := splitTag(word); ok {
+			tok.kind = tokenTag
+			tok.key = key
+			tok.value = value
+		}
+	}
+	return tok
+}
+
+func splitTag(word string) (string, string, bool) {
+	key, value, found := strings.Cut(word, ":")
+	if !found || !isTagKey(key) || value == "" {
+		return "", "", false
+	}
+	if strings.Contains(value, ":") || strings.HasPrefix(value, "//") {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+func isTagKey(key string) bool {
+	if key == "" || !isLetter(key[0]) {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		c := key[i]
+		if !isLetter(c) && !isDigit(c) && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func renderTokens(tokens []token) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			b.WriteString(tok.space)
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
+
+func descriptionOf(tokens []token) string {
+	var words []string
+	for _, tok := range tokens {
+		if tok.kind == tokenWord {
+			words = append(words, tok.text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// syncTokens reconciles the parsed tokens with the exported fields, which
+// callers are free to modify directly. Tokens that still match are kept in
+// place with their original spacing, changed tags are rewritten where they
+// stand, removed items are dropped and new ones are appended.
+//
+// Tags holds one value per key, that of the key's last occurrence, so only
+// that occurrence follows a changed value; earlier duplicates are kept as
+// they are unless the key is removed.
+func (t *Todo) syncTokens() []token {
+	var out []token
+	push := func(tok token) {
+		if tok.space == "" {
+			tok.space = " "
+		}
+		out = append(out, tok)
+	}
+
+	descWords := strings.Fields(t.Description)
+	descChanged := descriptionOf(t.tokens) != strings.Join(descWords, " ")
+	descWritten := false
+	writeDescription := func(space string) {
+		for i, word := range descWords {
+			tok := token{kind: tokenWord, text: word}
+			if i == 0 {
+				tok.space = space
+			}
+			push(tok)
+		}
+		descWritten = true
+	}
+
+	if descChanged && !hasWord(t.tokens) {
+		writeDescription("")
+	}
+
+	seenProjects := make(map[string]bool)
+	seenContexts := make(map[string]bool)
+	seenTags := make(map[string]bool)
+	lastTag := make(map[string]int)
+	for i, tok := range t.tokens {
+		if tok.kind == tokenTag {
+			lastTag[tok.key] = i
+		}
+	}
+
+	for i, tok := range t.tokens {
+		switch tok.kind {
+		case tokenWord:
+			if descChanged {
+				if !descWritten {
+					writeDescription(tok.space)
+				}
+				continue
+			}
+			push(tok)
+		case tokenProject:
+			if containsString(t.Projects, tok.value) {
+				seenProjects[tok.value] = true
+				push(tok)
+			}
+		case tokenContext:
+			if containsString(t.Contexts, tok.value) {
+				seenContexts[tok.value] = true
+				push(tok)
+			}
+		case tokenTag:
+			value, ok := t.Tags[tok.key]
+			if !ok || value == "" {
+				continue
+			}
+			if i == lastTag[tok.key] && value != tok.value {
+				tok.value = value
+				tok.text = tok.key + ":" + value
+			}
+			seenTags[tok.key] = true
+			push(tok)
+		}
+	}
+
+	for _, project := range t.Projects {
+		if !seenProjects[project] && !hasWordText(out, "+"+project) {
+			push(token{kind: tokenProject, text: "+" + project, value: project})
+		}
+	}
+
+	for _, context := range t.Contexts {
+		if !seenContexts[context] && !hasWordText(out, "@"+context) {
+			push(token{kind: tokenContext, text: "@" + context, value: context})
+		}
+	}
+
+	var keys []string
+	for key, value := range t.Tags {
+		if !seenTags[key] && value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := t.Tags[key]
+		push(token{kind: tokenTag, text: key + ":" + value, key: key, value: value})
+	}
+
+	return out
+}
+
+func hasWord(tokens []token) bool {
+	for _, tok := range tokens {
+		if tok.kind == tokenWord {
+			return true
+		}
+	}
+	return false
+}
+
+func hasWordText(tokens []token, text string) bool {
+	for _, tok := range tokens {
+		if tok.kind == tokenWord && tok.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML calls set for every key in r with the path of its table, in
+// file order, and with an empty key for every table header. Values are
+// strings, bools or int64s.
+func parseTOML(r io.Reader, set func(table []string, key string, value any) error) error {
+	scanner := bufio.NewScanner(r)
+	var table []string
+	seenTables := make(map[string]bool)
+	seenKeys := make(map[string]bool)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		err := func() error {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || line[0] == '#' {
+				return nil
+			}
+
+			if line[0] == '[' {
+				path, rest, err := parseTOMLKey(line[1:], ']')
+				if err != nil {
+					return err
+				}
+				if err := tomlTrailing(rest); err != nil {
+					return err
+				}
+				name := strings.Join(path, ".")
+				if seenTables[name] {
+					return fmt.Errorf("duplicate table [%s]", name)
+				}
+				seenTables[name] = true
+				table = path
+				return set(table, "", nil)
+			}
+
+			path, rest, err := parseTOMLKey(line, '=')
+			if err != nil {
+				return err
+			}
+			if len(path) != 1 {
+				return fmt.Errorf("dotted keys are not supported")
+			}
+			value, rest, err := parseTOMLValue(strings.TrimSpace(rest))
+			if err != nil {
+				return err
+			}
+			if err := tomlTrailing(rest); err != nil {
+				return err
+			}
+
+			full := strings.Join(append(append([]string(nil), table...), path[0]), ".")
+			if seenKeys[full] {
+				return fmt.Errorf("duplicate key %q", path[0])
+			}
+			seenKeys[full] = true
+			return set(table, path[0], value)
+		}()
+		if err != nil {
+			return fmt.Errorf("%d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseTOMLKey parses a dotted key of bare or quoted parts ending at end,
+// and returns the parts and the text after end.
+func parseTOMLKey(s string, end byte) ([]string, string, error) {
+	var parts []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		var part string
+		switch {
+		case s == "":
+			return nil, "", fmt.Errorf("missing %q", end)
+		case s[0] == '"' || s[0] == '\'':
+			value, rest, err := parseTOMLString(s)
+			if err != nil {
+				return nil, "", err
+			}
+			part, s = value, rest
+		default:
+			i := 0
+			for i < len(s) && isBareKeyByte(s[i]) {
+				i++
+			}
+			if i == 0 {
+				return nil, "", fmt.Errorf("invalid key at %q", s)
+			}
+			part, s = s[:i], s[i:]
+		}
+		parts = append(parts, part)
+
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case s != "" && s[0] == '.':
+			s = s[1:]
+		case s != "" && s[0] == end:
+			return parts, s[1:], nil
+		default:
+			return nil, "", fmt.Errorf("expected %q after key %q", end, part)
+		}
+	}
+}
+
+func isBareKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func parseTOMLValue(s string) (any, string, error) {
+	if s == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		return parseTOMLString(s)
+	}
+
+	end := strings.IndexAny(s, " \t#")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	if n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64); err == nil {
+		return n, rest, nil
+	}
+	return nil, "", fmt.Errorf("unsupported value %q (want a string, boolean or integer)", word)
+}
+
+// parseTOMLString parses a basic ("...") or literal ('...') string at the
+// start of s.
+func parseTOMLString(s string) (string, string, error) {
+	quote := s[0]
+	if quote == '\'' {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", fmt.Errorf("unterminated string")
+			}
+			i++
+			switch s[i] {
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'u', 'U':
+				size := 4
+				if s[i] == 'U' {
+					size = 8
+				}
+				if i+size >= len(s) {
+					return "", "", fmt.Errorf("invalid escape \\%c", s[i])
+				}
+				code, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(code)) {
+					return "", "", fmt.Errorf("invalid escape \\%s", s[i:i+1+size])
+				}
+				b.WriteRune(rune(code))
+				i += size
+			default:
+				return "", "", fmt.Errorf("invalid escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// tomlTrailing checks that only a comment follows a value or table header.
+func tomlTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q", rest)
+	}
+	return nil
+}
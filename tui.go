@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+const (
+	tuiSideWidth    = 28
+	tuiPollInterval = 2 * time.Second
+
+	ansiReset   = "\x1b[0m"
+	ansiReverse = "\x1b[7m"
+	ansiDim     = "\x1b[2m"
+	ansiBold    = "\x1b[1m"
+)
+
+const tuiKeyHelp = "j/k move  x do  u undo  +/- pri  p set pri  e edit  a add  d delete  A archive  / filter  q quit"
+
+// tui is the state of the interactive task browser. The todo file is only
+// locked while a change is applied, so other todotxt commands keep working
+// while it is open.
+type tui struct {
+	filter string
+	todos  []*todotxt.Todo
+	cursor int
+	offset int
+	status string
+	prompt string
+
+	// rows and cols are the terminal size, read again on resized.
+	rows    int
+	cols    int
+	resized <-chan os.Signal
+
+	keys chan string
+	out  *bufio.Writer
+}
+
+func tuiCommand(args []string) error {
+	// The lock taken at startup is re-taken for each change.
+	todoFile.Unlock()
+
+	ui := &tui{
+		filter: strings.Join(args, " "),
+		keys:   make(chan string),
+		out:    bufio.NewWriter(os.Stdout),
+	}
+	if err := ui.refresh(); err != nil {
+		return err
+	}
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	resized, stop := watchTerminalSize()
+	defer stop()
+	ui.resized = resized
+	ui.rows, ui.cols = terminalSize()
+
+	ui.out.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		ui.out.WriteString("\x1b[?25h\x1b[?1049l")
+		ui.out.Flush()
+	}()
+
+	go ui.readKeys()
+
+	ticker := time.NewTicker(tuiPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ui.render()
+
+		select {
+		case key, ok := <-ui.keys:
+			if !ok {
+				return nil
+			}
+			ui.reloadIfChanged()
+			if !ui.handle(key) {
+				return nil
+			}
+		case <-ui.resized:
+			ui.rows, ui.cols = terminalSize()
+		case <-ticker.C:
+			ui.reloadIfChanged()
+		}
+	}
+}
+
+// readKeys turns terminal input into key names: single characters, or
+// "up", "down", "pgup", "pgdn", "home", "end", "enter", "esc",
+// "backspace", "ctrl-c" and "ctrl-u".
+func (ui *tui) readKeys() {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(ui.keys)
+			return
+		}
+		for in := buf[:n]; len(in) > 0; {
+			key, size := decodeKey(in)
+			in = in[size:]
+			if key != "" {
+				ui.keys <- key
+			}
+		}
+	}
+}
+
+func decodeKey(in []byte) (string, int) {
+	escapes := map[string]string{
+		"\x1b[A": "up", "\x1b[B": "down", "\x1b[5~": "pgup", "\x1b[6~": "pgdn",
+		"\x1b[H": "home", "\x1b[F": "end", "\x1bOA": "up", "\x1bOB": "down",
+	}
+	if in[0] == 0x1b {
+		for seq, name := range escapes {
+			if strings.HasPrefix(string(in), seq) {
+				return name, len(seq)
+			}
+		}
+		if len(in) > 1 && (in[1] == '[' || in[1] == 'O') {
+			// Skip other escape sequences such as the remaining arrows.
+			i := 2
+			for i < len(in) && (in[i] < 0x40 || in[i] > 0x7e) {
+				i++
+			}
+			return "", min(i+1, len(in))
+		}
+		return "esc", 1
+	}
+
+	switch in[0] {
+	case '\r', '\n':
+		return "enter", 1
+	case 0x7f, 0x08:
+		return "backspace", 1
+	case 0x03:
+		return "ctrl-c", 1
+	case 0x15:
+		return "ctrl-u", 1
+	}
+	if in[0] < 0x20 {
+		return "", 1
+	}
+
+	r, size := utf8.DecodeRune(in)
+	return string(r), size
+}
+
+func (ui *tui) nextKey() (string, bool) {
+	for {
+		select {
+		case key, ok := <-ui.keys:
+			return key, ok
+		case <-ui.resized:
+			ui.rows, ui.cols = terminalSize()
+			ui.render()
+		}
+	}
+}
+
+// handle runs the command bound to key and reports whether to keep going.
+func (ui *tui) handle(key string) bool {
+	ui.status = ""
+	selected := ui.selected()
+
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "j", "down":
+		ui.move(1)
+	case "k", "up":
+		ui.move(-1)
+	case "pgdn", " ":
+		ui.move(ui.listHeight())
+	case "pgup":
+		ui.move(-ui.listHeight())
+	case "g", "home":
+		ui.move(-len(ui.todos))
+	case "G", "end":
+		ui.move(len(ui.todos))
+	case "r":
+		ui.reload("Reloaded")
+	case "/":
+		ui.editFilter()
+	case "a":
+		if line, ok := ui.readLine("Add: ", "", nil); ok && strings.TrimSpace(line) != "" {
+			todo, _ := todotxt.ParseTodo(line)
+			ui.apply(todo, "add", []string{line}, func() error {
+				if err := todo.ResolveDates(todotxt.Now()); err != nil {
+					return err
+				}
+				return addTask(todo)
+			})
+		}
+	case "A":
+		if ui.confirm("Archive all completed tasks?") {
+			ui.apply(selected, "archive", nil, func() error {
+				archived, err := archiveCompleted()
+				ui.status = fmt.Sprintf("Archived %d completed task(s)", archived)
+				return err
+			})
+		}
+	}
+
+	if selected == nil {
+		return true
+	}
+	ref := strconv.Itoa(selected.ID)
+
+	switch key {
+	case "x":
+		ui.apply(selected, "do", []string{ref}, func() error {
+			next, err := completeTodo(selected)
+			if next != nil {
+				ui.status = fmt.Sprintf("Next: %d: %s", next.ID, next.String())
+			}
+			return err
+		})
+	case "u":
+		ui.apply(selected, "undo", []string{ref}, func() error {
+			selected.MarkUncomplete()
+			return nil
+		})
+	case "+", "-", "p":
+		if selected.Complete {
+			ui.status = "Completed tasks cannot have priorities"
+			break
+		}
+		priority := selected.Priority
+		switch key {
+		case "+":
+			priority = raisePriority(priority)
+		case "-":
+			priority = lowerPriority(priority)
+		case "p":
+			ui.prompt = "Priority (A-Z, space to remove): "
+			ui.render()
+			k, _ := ui.nextKey()
+			ui.prompt = ""
+			switch {
+			case k == " ":
+				priority = todotxt.PriorityNone
+			case len(k) == 1 && strings.ToUpper(k)[0] >= 'A' && strings.ToUpper(k)[0] <= 'Z':
+				priority = todotxt.Priority(strings.ToUpper(k)[0])
+			default:
+				return true
+			}
+		}
+		if priority == selected.Priority {
+			break
+		}
+		name, args := "pri", []string{ref, string(rune(priority))}
+		if priority == todotxt.PriorityNone {
+			name, args = "depri", []string{ref}
+		}
+		ui.apply(selected, name, args, func() error {
+			selected.SetPriority(priority)
+			return nil
+		})
+	case "e", "enter":
+		line, ok := ui.readLine("Edit: ", selected.String(), nil)
+		if !ok || strings.TrimSpace(line) == "" || line == selected.String() {
+			break
+		}
+		todo, _ := todotxt.ParseTodo(line)
+		ui.apply(todo, "edit", []string{ref}, func() error {
+			if err := todo.ResolveDates(todotxt.Now()); err != nil {
+				return err
+			}
+			if !todoFile.Replace(selected, todo) {
+				return fmt.Errorf("task %s not found", ref)
+			}
+			return nil
+		})
+	case "d":
+		if ui.confirm(fmt.Sprintf("Delete task %s?", ref)) {
+			ui.apply(nil, "delete", []string{ref}, func() error {
+				if !todoFile.Delete(selected.ID) {
+					return fmt.Errorf("failed to delete task %s", ref)
+				}
+				return nil
+			})
+		}
+	}
+	return true
+}
+
+func raisePriority(p todotxt.Priority) todotxt.Priority {
+	switch {
+	case p == todotxt.PriorityNone:
+		return 'Z'
+	case p > 'A':
+		return p - 1
+	}
+	return p
+}
+
+func lowerPriority(p todotxt.Priority) todotxt.Priority {
+	switch {
+	case p == todotxt.PriorityNone || p == 'Z':
+		return todotxt.PriorityNone
+	}
+	return p + 1
+}
+
+// apply makes one change under the todo file lock, saves it and records it
+// in the journal as the equivalent command, so undo-last can revert it.
+// If the file changed on disk since it was shown, it is reloaded instead.
+// keep is the task to leave the cursor on afterwards.
+func (ui *tui) apply(keep *todotxt.Todo, name string, args []string, fn func() error) {
+	err := func() error {
+		if err := todoFile.Lock(); err != nil {
+			return err
+		}
+		defer todoFile.Unlock()
+
+		if changed, err := todoFile.Changed(); err != nil || changed {
+			if err := todoFile.Load(); err != nil {
+				return err
+			}
+			return fmt.Errorf("todo.txt changed on disk and was reloaded; nothing was changed")
+		}
+
+		err := runJournaled(name, args, func([]string) error {
+			if err := fn(); err != nil {
+				return err
+			}
+			return saveFile()
+		})
+		if err != nil {
+			todoFile.Load()
+		}
+		return err
+	}()
+	if err != nil {
+		ui.status = "Error: " + err.Error()
+	}
+
+	ui.refresh()
+	if keep != nil {
+		for i, todo := range ui.todos {
+			if todo == keep {
+				ui.cursor = i
+			}
+		}
+	}
+}
+
+func (ui *tui) reloadIfChanged() {
+	if changed, err := todoFile.Changed(); err == nil && changed {
+		ui.reload("Reloaded changes from disk")
+	}
+}
+
+func (ui *tui) reload(status string) {
+	if err := todoFile.Lock(); err != nil {
+		ui.status = "Error: " + err.Error()
+		return
+	}
+	err := todoFile.Load()
+	todoFile.Unlock()
+	if err != nil {
+		ui.status = "Error: " + err.Error()
+		return
+	}
+	ui.status = status
+	ui.refresh()
+}
+
+// refresh recomputes the visible tasks with the same rules as list.
+func (ui *tui) refresh() error {
+	var args []string
+	if filter := strings.TrimSpace(ui.filter); filter != "" {
+		args = []string{filter}
+	}
+	todos, err := filterTodos(args, false)
+	if err != nil {
+		return err
+	}
+	if settings.Sort != "" {
+		keys, err := todotxt.ParseSortKeys(settings.Sort)
+		if err != nil {
+			return err
+		}
+		todotxt.SortTodosBy(todos, keys)
+	}
+	ui.todos = todos
+	ui.move(0)
+	return nil
+}
+
+func (ui *tui) editFilter() {
+	previous := ui.filter
+	_, ok := ui.readLine("Filter: ", ui.filter, func(filter string) {
+		ui.filter = filter
+		if err := ui.refresh(); err != nil {
+			ui.status = err.Error()
+		} else {
+			ui.status = ""
+		}
+	})
+	if !ok || ui.refresh() != nil {
+		ui.filter = previous
+		ui.refresh()
+	}
+}
+
+func (ui *tui) selected() *todotxt.Todo {
+	if ui.cursor < 0 || ui.cursor >= len(ui.todos) {
+		return nil
+	}
+	return ui.todos[ui.cursor]
+}
+
+func (ui *tui) move(delta int) {
+	ui.cursor = max(0, min(ui.cursor+delta, len(ui.todos)-1))
+}
+
+// readLine edits a line of text in the prompt row. onChange, if set, is
+// called after every edit. It reports false if the edit was cancelled.
+func (ui *tui) readLine(label, initial string, onChange func(string)) (string, bool) {
+	line := initial
+	defer func() { ui.prompt = "" }()
+	for {
+		ui.prompt = label + line + "_"
+		ui.render()
+
+		key, ok := ui.nextKey()
+		switch {
+		case !ok || key == "esc" || key == "ctrl-c":
+			return "", false
+		case key == "enter":
+			return line, true
+		case key == "backspace":
+			if line != "" {
+				_, size := utf8.DecodeLastRuneInString(line)
+				line = line[:len(line)-size]
+			}
+		case key == "ctrl-u":
+			line = ""
+		case utf8.RuneCountInString(key) == 1:
+			line += key
+		default:
+			continue
+		}
+		if onChange != nil {
+			onChange(line)
+		}
+	}
+}
+
+func (ui *tui) confirm(question string) bool {
+	ui.prompt = question + " (y/n)"
+	ui.render()
+	key, _ := ui.nextKey()
+	ui.prompt = ""
+	return key == "y" || key == "Y"
+}
+
+func (ui *tui) listHeight() int {
+	return max(ui.rows-3, 1)
+}
+
+func (ui *tui) render() {
+	cols := ui.cols
+	height := ui.listHeight()
+
+	side := tuiSideWidth
+	if cols < 2*tuiSideWidth {
+		side = 0
+	}
+	listWidth := cols
+	if side > 0 {
+		listWidth = cols - side - 1
+	}
+
+	if ui.cursor < ui.offset {
+		ui.offset = ui.cursor
+	}
+	if ui.cursor >= ui.offset+height {
+		ui.offset = ui.cursor - height + 1
+	}
+
+	w := ui.out
+	w.WriteString("\x1b[H\x1b[2J")
+
+	filter := ui.filter
+	if filter == "" {
+		filter = "(open tasks)"
+	}
+	header := fmt.Sprintf(" todotxt  %s  filter: %s  %d task(s)", todoFile.Path, filter, len(ui.todos))
+	w.WriteString(ansiReverse + fit(header, cols) + ansiReset + "\r\n")
+
+	pane := sidePane(ui.todos)
+	for row := 0; row < height; row++ {
+		i := ui.offset + row
+		cell := ""
+		style := ""
+		if i < len(ui.todos) {
+			todo := ui.todos[i]
+			status := " "
+			if todo.Complete {
+				status = "x"
+				style = ansiDim
+			}
+			cell = fmt.Sprintf("[%s] %3d: %s", status, todo.ID, todo.String())
+			if i == ui.cursor {
+				style = ansiReverse
+			}
+		} else if i == 0 {
+			cell = "No tasks found."
+		}
+		w.WriteString(style + fit(cell, listWidth) + ansiReset)
+
+		if side > 0 {
+			line := ""
+			if row < len(pane) {
+				line = pane[row]
+			}
+			if line == "Projects" || line == "Contexts" {
+				line = ansiBold + fit(line, side) + ansiReset
+			} else {
+				line = fit(line, side)
+			}
+			w.WriteString(ansiDim + "│" + ansiReset + line)
+		}
+		w.WriteString("\r\n")
+	}
+
+	w.WriteString(fit(ui.status, cols) + "\r\n")
+	if ui.prompt != "" {
+		w.WriteString(fit(ui.prompt, cols))
+	} else {
+		w.WriteString(ansiDim + fit(tuiKeyHelp, cols) + ansiReset)
+	}
+	w.Flush()
+}
+
+// sidePane lists the projects and contexts of todos with task counts.
+func sidePane(todos []*todotxt.Todo) []string {
+	projects := make(map[string]int)
+	contexts := make(map[string]int)
+	for _, todo := range todos {
+		for _, project := range todo.Projects {
+			projects[project]++
+		}
+		for _, context := range todo.Contexts {
+			contexts[context]++
+		}
+	}
+
+	lines := []string{"Projects"}
+	lines = append(lines, countLines("+", projects)...)
+	lines = append(lines, "", "Contexts")
+	lines = append(lines, countLines("@", contexts)...)
+	return lines
+}
+
+func countLines(prefix string, counts map[string]int) []string {
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return []string{"  (none)"}
+	}
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s%s %d", prefix, name, counts[name]))
+	}
+	return lines
+}
+
+// fit truncates or pads s to width runes.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
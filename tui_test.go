@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuniyoshi/todotxt/todotxt"
+)
+
+func TestDecodeKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "jx", expected: []string{"j", "x"}},
+		{input: "\x1b[A\x1b[B\x1bOA", expected: []string{"up", "down", "up"}},
+		{input: "\x1b[5~\x1b[6~\x1b[H\x1b[F", expected: []string{"pgup", "pgdn", "home", "end"}},
+		{input: "\x1b[C\x1b[1;5Dq", expected: []string{"q"}},
+		{input: "\x1b", expected: []string{"esc"}},
+		{input: "\r\n\x7f\x08\x03\x15", expected: []string{"enter", "enter", "backspace", "backspace", "ctrl-c", "ctrl-u"}},
+		{input: "\x01é", expected: []string{"é"}},
+	}
+
+	for _, tt := range tests {
+		var keys []string
+		for in := []byte(tt.input); len(in) > 0; {
+			key, size := decodeKey(in)
+			in = in[size:]
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if !reflect.DeepEqual(keys, tt.expected) {
+			t.Errorf("decodeKey(%q) = %q, want %q", tt.input, keys, tt.expected)
+		}
+	}
+}
+
+// typed returns the keys for typing text.
+func typed(text string) []string {
+	return strings.Split(text, "")
+}
+
+func TestTUIKeys(t *testing.T) {
+	lines := []string{
+		"(B) Call Mom +Family",
+		"Pay rent due:2025-01-09",
+		"Water plants",
+	}
+	unchanged := strings.Join(lines, "\n") + "\n"
+
+	keys := func(groups ...[]string) []string {
+		var all []string
+		for _, group := range groups {
+			all = append(all, group...)
+		}
+		return all
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		file     string
+		quit     bool
+		selected string
+	}{
+		{name: "Complete", keys: []string{"x"}, file: "x 2025-01-10 Call Mom +Family\n" + lines[1] + "\n" + lines[2] + "\n", selected: "Pay rent"},
+		{name: "Move down and complete", keys: []string{"j", "down", "x"}, file: lines[0] + "\n" + lines[1] + "\nx 2025-01-10 Water plants\n"},
+		{name: "Moves stop at the ends", keys: []string{"G", "j", "k", "k", "k", "home", "j"}, file: unchanged, selected: "Pay rent"},
+		{name: "Raise priority", keys: []string{"+"}, file: "(A) Call Mom +Family\n" + lines[1] + "\n" + lines[2] + "\n", selected: "Call Mom"},
+		{name: "Lower priority", keys: []string{"-"}, file: "(C) Call Mom +Family\n" + lines[1] + "\n" + lines[2] + "\n"},
+		{name: "First priority", keys: []string{"j", "+"}, file: lines[0] + "\n(Z) Pay rent due:2025-01-09\n" + lines[2] + "\n", selected: "Pay rent"},
+		{name: "Set priority", keys: []string{"p", "d"}, file: "(D) Call Mom +Family\n" + lines[1] + "\n" + lines[2] + "\n"},
+		{name: "Remove priority", keys: []string{"p", " "}, file: "Call Mom +Family\n" + lines[1] + "\n" + lines[2] + "\n"},
+		{name: "Invalid priority", keys: []string{"p", "1"}, file: unchanged},
+		{name: "Delete confirmed", keys: []string{"j", "d", "y"}, file: lines[0] + "\n" + lines[2] + "\n"},
+		{name: "Delete declined", keys: []string{"d", "n"}, file: unchanged},
+		{name: "Add", keys: keys([]string{"a"}, typed("Buy milk due:tomorrow"), []string{"enter"}), file: unchanged + "Buy milk due:2025-01-11\n"},
+		{name: "Add with backspace", keys: keys([]string{"a"}, typed("Buy milkk"), []string{"backspace", "enter"}), file: unchanged + "Buy milk\n"},
+		{name: "Add cancelled", keys: keys([]string{"a"}, typed("Buy milk"), []string{"esc"}), file: unchanged},
+		{name: "Edit", keys: keys([]string{"j", "e", "ctrl-u"}, typed("Pay rent +Home"), []string{"enter"}), file: lines[0] + "\nPay rent +Home\n" + lines[2] + "\n"},
+		{name: "Filter then complete", keys: keys([]string{"/"}, typed("Water"), []string{"enter", "x"}), file: lines[0] + "\n" + lines[1] + "\nx 2025-01-10 Water plants\n"},
+		{name: "Filter after all", keys: keys([]string{"/"}, typed("all Water"), []string{"enter", "x"}), file: lines[0] + "\n" + lines[1] + "\nx 2025-01-10 Water plants\n"},
+		{name: "Invalid filter is dropped", keys: keys([]string{"/"}, typed("(Water"), []string{"enter", "x"}), file: "x 2025-01-10 Call Mom +Family\n" + lines[1] + "\n" + lines[2] + "\n"},
+		{name: "Archive", keys: []string{"x", "A", "y"}, file: lines[1] + "\n" + lines[2] + "\n"},
+		{name: "Quit", keys: []string{"j", "q", "x"}, file: unchanged, quit: true},
+		{name: "Interrupt", keys: []string{"ctrl-c"}, file: unchanged, quit: true},
+	}
+
+	defer todotxt.SetClock(todotxt.SetClock(todotxt.FixedClock(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC))))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestTodoFile(t, lines...)
+			ui := &tui{rows: 24, cols: 80, keys: make(chan string, len(tt.keys)), out: bufio.NewWriter(io.Discard)}
+			if err := ui.refresh(); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.keys {
+				ui.keys <- key
+			}
+			close(ui.keys)
+
+			quit := false
+			for key := range ui.keys {
+				if !ui.handle(key) {
+					quit = true
+					break
+				}
+			}
+			if quit != tt.quit {
+				t.Errorf("quit = %v, want %v", quit, tt.quit)
+			}
+			if ui.status != "" && strings.HasPrefix(ui.status, "Error") {
+				t.Errorf("Unexpected status %q", ui.status)
+			}
+			if got := readTodoFile(t); got != tt.file {
+				t.Errorf("todo.txt = %q, want %q", got, tt.file)
+			}
+			if tt.selected != "" {
+				if selected := ui.selected(); selected == nil || selected.Description != tt.selected {
+					t.Errorf("selected = %v, want %q", selected, tt.selected)
+				}
+			}
+		})
+	}
+}
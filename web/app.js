@@ -0,0 +1,206 @@
+"use strict";
+
+// State of the page: the filter and sort are sent to /api/tasks, which
+// applies them with the same rules as the list command.
+const state = {
+  filter: "",
+  sort: "priority",
+};
+
+const $ = (id) => document.getElementById(id);
+
+async function api(method, path, body) {
+  const options = { method, headers: {} };
+  // The server only accepts changes with a JSON body.
+  if (method !== "GET") {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body === undefined ? {} : body);
+  }
+  const response = await fetch(path, options);
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.error || response.statusText);
+  }
+  return data;
+}
+
+function showError(err) {
+  $("status").textContent = err ? err.message : "";
+}
+
+async function refresh() {
+  const params = new URLSearchParams();
+  if (state.filter) params.set("q", state.filter);
+  if (state.sort) params.set("sort", state.sort);
+
+  try {
+    const all = state.filter === "all" || state.filter === "done" ? "?all=true" : "";
+    const [tasks, projects, contexts] = await Promise.all([
+      api("GET", "/api/tasks?" + params),
+      api("GET", "/api/projects" + all),
+      api("GET", "/api/contexts" + all),
+    ]);
+    renderTasks(tasks);
+    renderSidebar($("projects"), projects, "+");
+    renderSidebar($("contexts"), contexts, "@");
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+  renderSortHeaders();
+}
+
+function cell(row, content, className) {
+  const td = row.insertCell();
+  if (className) td.className = className;
+  if (content instanceof Node) {
+    td.appendChild(content);
+  } else {
+    td.textContent = content;
+  }
+  return td;
+}
+
+function tagLinks(prefix, names) {
+  const span = document.createElement("span");
+  for (const name of names) {
+    const link = document.createElement("span");
+    link.className = "tag";
+    link.textContent = prefix + name;
+    link.addEventListener("click", () => setFilter(prefix + name));
+    span.appendChild(link);
+  }
+  return span;
+}
+
+function renderTasks(tasks) {
+  const body = $("tasks");
+  body.replaceChildren();
+
+  for (const task of tasks) {
+    const row = body.insertRow();
+    if (task.complete) row.className = "complete";
+
+    // Line numbers shift when other programs edit the file, so changes
+    // carry the line as it was shown and the server refuses them with a
+    // conflict if the task is no longer that line.
+    const ref = task.tags.id || String(task.id);
+    const path = "/api/tasks/" + encodeURIComponent(ref) + "?" + new URLSearchParams({ expect: task.text });
+
+    const done = document.createElement("input");
+    done.type = "checkbox";
+    done.checked = task.complete;
+    done.addEventListener("change", () => update(path, { complete: done.checked }));
+    cell(row, done);
+
+    const priority = document.createElement("select");
+    priority.disabled = task.complete;
+    for (const letter of ["", ..."ABCDEFGHIJKLMNOPQRSTUVWXYZ"]) {
+      const option = new Option(letter || "-", letter, false, letter === (task.priority || ""));
+      priority.add(option);
+    }
+    priority.addEventListener("change", () => update(path, { priority: priority.value }));
+    cell(row, priority);
+
+    cell(row, String(task.id));
+    cell(row, task.description, "description").title = task.text;
+    cell(row, tagLinks("+", task.projects));
+    cell(row, tagLinks("@", task.contexts));
+    cell(row, task.tags.due || "");
+  }
+
+  if (tasks.length === 0) {
+    const row = body.insertRow();
+    const td = cell(row, "No tasks found.");
+    td.colSpan = 7;
+  }
+}
+
+function renderSidebar(list, counts, prefix) {
+  list.replaceChildren();
+  for (const { name, count } of counts) {
+    if (name === "") continue;
+    const item = document.createElement("li");
+    const label = document.createElement("span");
+    label.textContent = prefix + name;
+    const number = document.createElement("span");
+    number.className = "count";
+    number.textContent = count;
+    item.append(label, number);
+    if (state.filter === prefix + name) item.className = "active";
+    item.addEventListener("click", () => setFilter(state.filter === prefix + name ? "" : prefix + name));
+    list.appendChild(item);
+  }
+}
+
+function renderSortHeaders() {
+  const key = state.sort.replace(/^-/, "");
+  const descending = state.sort.startsWith("-");
+  for (const th of document.querySelectorAll("th[data-sort]")) {
+    th.classList.toggle("asc", th.dataset.sort === key && !descending);
+    th.classList.toggle("desc", th.dataset.sort === key && descending);
+  }
+}
+
+function setFilter(filter) {
+  state.filter = filter;
+  $("filter").value = filter;
+  refresh();
+}
+
+async function update(path, patch) {
+  try {
+    await api("PATCH", path, patch);
+  } catch (err) {
+    showError(err);
+  }
+  refresh();
+}
+
+let filterTimer;
+$("filter").addEventListener("input", (event) => {
+  clearTimeout(filterTimer);
+  filterTimer = setTimeout(() => {
+    state.filter = event.target.value.trim();
+    refresh();
+  }, 200);
+});
+
+for (const th of document.querySelectorAll("th[data-sort]")) {
+  th.addEventListener("click", () => {
+    const key = th.dataset.sort;
+    state.sort = state.sort === key ? "-" + key : key;
+    refresh();
+  });
+}
+
+$("add").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const text = $("add-text").value.trim();
+  if (!text) return;
+  try {
+    await api("POST", "/api/tasks", { text });
+    $("add-text").value = "";
+  } catch (err) {
+    showError(err);
+  }
+  refresh();
+});
+
+$("archive").addEventListener("click", async () => {
+  try {
+    await api("POST", "/api/archive");
+  } catch (err) {
+    showError(err);
+  }
+  refresh();
+});
+
+// Pick up changes made by other programs while the page is open. Skip
+// while a priority menu is open so the table is not rebuilt under it.
+setInterval(() => {
+  const editing = document.activeElement && document.activeElement.tagName === "SELECT";
+  if (!document.hidden && !editing) refresh();
+}, 5000);
+
+refresh();